@@ -1,6 +1,10 @@
 package cloudfoundry
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
 	"code.cloudfoundry.org/cli/resources"
 )
@@ -62,3 +66,60 @@ func (c *Client) DeleteApplication(guid string) (ccv3.JobURL, error) {
 	c.listWarnings(warn)
 	return jobUrl, err
 }
+
+// UpdateApplicationPorts sets the ports the app listens on. The ports of Docker apps are otherwise taken
+// from the image, or default to 8080. This is only available on the v2 API, which some foundations disable.
+func (c *Client) UpdateApplicationPorts(guid string, ports []int) error {
+	available, err := c.v2Available()
+	if err != nil {
+		return fmt.Errorf("unable to get the APIs of the Cloud Controller: %v", err)
+	}
+	if !available {
+		return fmt.Errorf("the v2 API of the Cloud Controller is disabled, app ports can only be set with it")
+	}
+
+	body, err := json.Marshal(struct {
+		Ports []int `json:"ports"`
+	}{Ports: ports})
+	if err != nil {
+		return err
+	}
+
+	raw, _, err := c.client.MakeRequestSendReceiveRaw(
+		http.MethodPut,
+		fmt.Sprintf("%s/v2/apps/%s", c.client.CloudControllerURL, guid),
+		http.Header{},
+		body,
+	)
+	if err != nil {
+		return v2Error(raw, err)
+	}
+	return nil
+}
+
+// v2Available tells whether the root of the Cloud Controller links to the v2 API
+func (c *Client) v2Available() (bool, error) {
+	var root struct {
+		Links map[string]*struct {
+			Href string `json:"href"`
+		} `json:"links"`
+	}
+	if err := c.request(http.MethodGet, "/", nil, &root); err != nil {
+		return false, err
+	}
+	link := root.Links["cloud_controller_v2"]
+	return link != nil && link.Href != "", nil
+}
+
+// v2Error returns the description of an error of the v2 API, as its errors aren't in the v3 format
+// parsed by the ccv3 client. err is returned if raw isn't a v2 error.
+func v2Error(raw []byte, err error) error {
+	var v2 struct {
+		Description string `json:"description"`
+		ErrorCode   string `json:"error_code"`
+	}
+	if json.Unmarshal(raw, &v2) != nil || v2.Description == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %s", v2.ErrorCode, v2.Description)
+}
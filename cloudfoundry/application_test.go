@@ -0,0 +1,87 @@
+package cloudfoundry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry/cftest"
+)
+
+// newTestClient returns a client of server, which has to serve the UAA token requests
+func newTestClient(t *testing.T, server *httptest.Server) *Client {
+	cftest.Target(t, server, "access-token", "refresh-token")
+	client, err := New(hclog.NewNullLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestUpdateApplicationPorts(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		v2      bool
+		failPut bool
+		err     string
+	}{
+		{name: "success", v2: true},
+		{
+			name: "v2 disabled",
+			err:  "the v2 API of the Cloud Controller is disabled, app ports can only be set with it",
+		},
+		{
+			name:    "v2 error",
+			v2:      true,
+			failPut: true,
+			err:     "CF-AppInvalid: The app is invalid: ports must be in the range 1024-65535",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var ports []int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.Method + " " + r.URL.Path {
+				case "POST /oauth/token":
+					_, _ = w.Write([]byte(`{"access_token": "access-token", "refresh_token": "refresh-token", "token_type": "bearer"}`))
+				case "GET /":
+					links := map[string]interface{}{"cloud_controller_v3": map[string]string{"href": "http://" + r.Host + "/v3"}}
+					if tc.v2 {
+						links["cloud_controller_v2"] = map[string]string{"href": "http://" + r.Host + "/v2"}
+					} else {
+						links["cloud_controller_v2"] = nil
+					}
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"links": links})
+				case "PUT /v2/apps/app-guid":
+					if tc.failPut {
+						w.WriteHeader(http.StatusBadRequest)
+						_, _ = w.Write([]byte(`{"code": 100001, "description": "The app is invalid: ports must be in the range 1024-65535", "error_code": "CF-AppInvalid"}`))
+						return
+					}
+					var body struct {
+						Ports []int `json:"ports"`
+					}
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					ports = body.Ports
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{}`))
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			err := newTestClient(t, server).UpdateApplicationPorts("app-guid", []int{9000, 9090})
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []int{9000, 9090}, ports)
+		})
+	}
+}
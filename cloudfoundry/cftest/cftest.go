@@ -0,0 +1,46 @@
+// Package cftest provides a fake Cloud Foundry target to the tests of the plugin
+package cftest
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Target writes the CF CLI configuration of a user logged in to server, which serves both the Cloud
+// Controller and UAA requests, and points CF_HOME to it until the end of the test
+func Target(t *testing.T, server *httptest.Server, accessToken, refreshToken string) {
+	home := t.TempDir()
+	t.Setenv("CF_HOME", home)
+	config, err := json.Marshal(map[string]interface{}{
+		"ConfigVersion":         4,
+		"Target":                server.URL,
+		"AuthorizationEndpoint": server.URL,
+		"UaaEndpoint":           server.URL,
+		"UAAOAuthClient":        "cf",
+		"AccessToken":           "bearer " + accessToken,
+		"RefreshToken":          refreshToken,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(home, ".cf"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".cf", "config.json"), config, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// List returns a page of the Cloud Controller listing resources
+func List(resources ...interface{}) interface{} {
+	if resources == nil {
+		resources = []interface{}{}
+	}
+	return map[string]interface{}{
+		"pagination": map[string]interface{}{"total_results": len(resources)},
+		"resources":  resources,
+	}
+}
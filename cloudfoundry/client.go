@@ -1,24 +1,39 @@
 package cloudfoundry
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
+	"code.cloudfoundry.org/cli/api/uaa"
 	"code.cloudfoundry.org/cli/resources"
+	"code.cloudfoundry.org/cli/util/configv3"
 	"github.com/hashicorp/go-hclog"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
 )
 
 type Client struct {
 	client *ccv3.Client
+	uaa    *uaa.Client
+	config *configv3.Config
+	tokens *tokenCache
 	logger hclog.Logger
 }
 
 func New(logger hclog.Logger) (*Client, error){
-	envClient, err := getEnvClient()
+	envClient, uaaClient, config, tokens, err := getEnvClientConfig()
 	if err != nil {
 		return nil, err
 	}
 
 	return &Client{
 		client: envClient,
+		uaa:    uaaClient,
+		config: config,
+		tokens: tokens,
 		logger: logger,
 	}, nil
 }
@@ -35,6 +50,14 @@ func (c *Client) CloudControllerURL() interface{} {
 	return c.client.CloudControllerURL
 }
 
+// Tokens returns the current access and refresh tokens of the client, without their type
+func (c *Client) Tokens() []string {
+	return []string{
+		strings.TrimPrefix(c.tokens.AccessToken(), "bearer "),
+		c.tokens.RefreshToken(),
+	}
+}
+
 func (c *Client) GetDomainsByName(names ...string) (domains []resources.Domain, err error) {
 	var warn ccv3.Warnings
 	domains, warn, err = c.client.GetDomains(ccv3.Query{
@@ -53,4 +76,57 @@ func (c *Client) GetProcessInstances(guid string) ([]ccv3.ProcessInstance, error
 	processInstances, warn, err := c.client.GetProcessInstances(guid)
 	c.listWarnings(warn)
 	return processInstances, err
-}
\ No newline at end of file
+}
+
+// maxConcurrentRequests bounds the number of requests sent at the same time to the Cloud Controller
+const maxConcurrentRequests = 8
+
+// GetProcessesInstances fetches the instances of several processes concurrently,
+// in the order of the given processes
+func (c *Client) GetProcessesInstances(processes []resources.Process) ([][]ccv3.ProcessInstance, error) {
+	result := make([][]ccv3.ProcessInstance, len(processes))
+	err := utils.ForEachConcurrently(len(processes), maxConcurrentRequests, func(i int) error {
+		instances, err := c.GetProcessInstances(processes[i].GUID)
+		if err != nil {
+			return fmt.Errorf("error getting instances of process %s (%s): %v",
+				processes[i].Type, processes[i].GUID, err)
+		}
+		result[i] = instances
+		return nil
+	})
+	return result, err
+}
+
+// request sends a raw JSON request to an endpoint of the Cloud Controller API which is
+// not covered by the ccv3 client. If out is not nil, the response body is decoded into it.
+func (c *Client) request(method string, path string, in interface{}, out interface{}) error {
+	return c.requestURL(method, c.client.CloudControllerURL+path, in, out)
+}
+
+// requestURL is like request, but for APIs which aren't served by the Cloud Controller
+// and share its authentication, such as the network policy API.
+func (c *Client) requestURL(method string, url string, in interface{}, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	raw, _, err := c.client.MakeRequestSendReceiveRaw(
+		method,
+		url,
+		http.Header{},
+		body,
+	)
+	if err != nil {
+		return err
+	}
+
+	if out == nil || len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
@@ -0,0 +1,104 @@
+package cloudfoundry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	uaaConstant "code.cloudfoundry.org/cli/api/uaa/constant"
+	"code.cloudfoundry.org/cli/util"
+)
+
+// CredHubClient reads credentials from the CredHub API, authenticated with UAA
+type CredHubClient struct {
+	url        string
+	httpClient *http.Client
+	token      func() (string, error)
+}
+
+// NewCredHubClient returns a client of the CredHub API at credHubUrl. If clientID is set, it authenticates
+// with these UAA client credentials, otherwise with the token of the Cloud Foundry user.
+func (c *Client) NewCredHubClient(credHubUrl string, clientID string, clientSecret string) *CredHubClient {
+	token := func() (string, error) {
+		return c.tokens.AccessToken(), nil
+	}
+
+	if clientID != "" {
+		var once sync.Once
+		var accessToken string
+		var err error
+		token = func() (string, error) {
+			once.Do(func() {
+				accessToken, _, err = c.uaa.Authenticate(map[string]string{
+					"client_id":     clientID,
+					"client_secret": clientSecret,
+				}, "", uaaConstant.GrantTypeClientCredentials)
+			})
+			if err != nil {
+				return "", fmt.Errorf("unable to authenticate client %s with UAA: %v", clientID, err)
+			}
+			return "bearer " + accessToken, nil
+		}
+	}
+
+	return &CredHubClient{
+		url: strings.TrimSuffix(credHubUrl, "/"),
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: util.NewTLSConfig(nil, c.config.SkipSSLValidation()),
+			},
+			Timeout: 30 * time.Second,
+		},
+		token: token,
+	}
+}
+
+// GetValue returns the current value of a credential: a string for value and password credentials,
+// an object for the other types (json, user, certificate, ...)
+func (ch *CredHubClient) GetValue(name string) (interface{}, error) {
+	token, err := ch.token()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet,
+		fmt.Sprintf("%s/api/v1/data?current=true&name=%s", ch.url, url.QueryEscape(name)), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", token)
+
+	resp, err := ch.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Error responses only contain a description of the error, not the credential
+		return nil, fmt.Errorf("unable to get credential %s: %s: %s", name, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Data []struct {
+			Value interface{} `json:"value"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("unable to decode credential %s: %v", name, err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("credential %s not found", name)
+	}
+	return result.Data[0].Value, nil
+}
@@ -8,16 +8,17 @@ import (
 	"code.cloudfoundry.org/cli/util/configv3"
 )
 
-func getEnvClientConfig() (*ccv3.Client, *configv3.Config, error) {
+func getEnvClientConfig() (*ccv3.Client, *uaa.Client, *configv3.Config, *tokenCache, error) {
 	config, err := configv3.LoadConfig(configv3.FlagOverride{})
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
-	var ccWrappers []ccv3.ConnectionWrapper
-	authWrapper := ccWrapper.NewUAAAuthentication(nil, config)
+	// Requests sent concurrently share the tokens of the config, see tokenCache
+	tokens := &tokenCache{cache: config}
 
-	ccWrappers = append(ccWrappers, authWrapper)
+	var ccWrappers []ccv3.ConnectionWrapper
+	ccWrappers = append(ccWrappers, &tokenAuthentication{tokens: tokens})
 	ccWrappers = append(ccWrappers, ccWrapper.NewRetryRequest(config.RequestRetryCount()))
 
 	ccClient := ccv3.NewClient(ccv3.Config{
@@ -35,21 +36,16 @@ func getEnvClientConfig() (*ccv3.Client, *configv3.Config, error) {
 	})
 
 	uaaClient := uaa.NewClient(config)
-	uaaAuthWrapper := uaaWrapper.NewUAAAuthentication(nil, config)
+	uaaAuthWrapper := uaaWrapper.NewUAAAuthentication(nil, tokens)
 	uaaClient.WrapConnection(uaaAuthWrapper)
 	uaaClient.WrapConnection(uaaWrapper.NewRetryRequest(config.RequestRetryCount()))
 
 	err = uaaClient.SetupResources(config.ConfigFile.UAAEndpoint, config.ConfigFile.AuthorizationEndpoint)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
-	uaaAuthWrapper.SetClient(uaaClient)
-	authWrapper.SetClient(uaaClient)
-	return ccClient, config, nil
-}
-
-func getEnvClient() (*ccv3.Client, error) {
-	client, _, err := getEnvClientConfig()
-	return client, err
+	tokens.client = uaaClient
+	uaaAuthWrapper.SetClient(tokens)
+	return ccClient, uaaClient, config, tokens, nil
 }
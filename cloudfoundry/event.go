@@ -0,0 +1,18 @@
+package cloudfoundry
+
+import (
+	"strconv"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
+)
+
+// GetAppEvents returns the latest audit events of an app, the most recent first
+func (c *Client) GetAppEvents(appGuid string, limit int) ([]ccv3.Event, error) {
+	events, warns, err := c.client.GetEvents(
+		ccv3.Query{Key: ccv3.TargetGUIDFilter, Values: []string{appGuid}},
+		ccv3.Query{Key: ccv3.OrderBy, Values: []string{ccv3.CreatedAtDescendingOrder}},
+		ccv3.Query{Key: ccv3.PerPage, Values: []string{strconv.Itoa(limit)}},
+	)
+	c.listWarnings(warns)
+	return events, err
+}
@@ -1,13 +1,36 @@
 package cloudfoundry
 
 import (
-	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3/constant"
+	"encoding/json"
 	"fmt"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3/constant"
+	"code.cloudfoundry.org/cli/resources"
 	proto "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
 )
 
-func (c *Client) GetHealthByGUID(appGuid string) (result *proto.StatusReport, err error){
+const platformName = "cloudfoundry"
+
+// GetHealthByGUID reports the health of an app, its processes and their instances. The health of the
+// processes and of the app is aggregated with policy, or from the instance states if policy is nil.
+func (c *Client) GetHealthByGUID(appGuid string, policy utils.HealthPolicy) (result *proto.StatusReport, err error) {
 	result = &proto.StatusReport{}
+
+	apps, warn, err := c.client.GetApplications(ccv3.Query{
+		Key:    ccv3.GUIDFilter,
+		Values: []string{appGuid},
+	})
+	c.listWarnings(warn)
+	if err != nil {
+		return nil, fmt.Errorf("error getting application %s: %v", appGuid, err)
+	}
+	if len(apps) != 1 {
+		return nil, fmt.Errorf("expected 1 application with GUID %s, found %d", appGuid, len(apps))
+	}
+	app := apps[0]
+
 	processes, warn, err := c.client.GetApplicationProcesses(appGuid)
 	if err != nil {
 		return nil, fmt.Errorf("error getting application processes: %v", err)
@@ -15,45 +38,121 @@ func (c *Client) GetHealthByGUID(appGuid string) (result *proto.StatusReport, er
 
 	c.listWarnings(warn)
 
-	processInstancesCount := 0
-	statusMap := map[constant.ProcessInstanceState]int{}
-
-	for _, proc := range processes {
-		// Get Health Check result
-		pInstances, warn, err := c.client.GetProcessInstances(proc.GUID)
-		if err != nil {
-			return nil, fmt.Errorf("error getting process instance for %s (part of %s): %s",
-				proc.GUID,
-				appGuid,
-				err,
-			)
-		}
-		c.listWarnings(warn)
+	var states []constant.ProcessInstanceState
+	var samples []utils.HealthSample
+	var processResources []*proto.StatusReport_Resource
+
+	instances, err := c.GetProcessesInstances(processes)
+	if err != nil {
+		return nil, fmt.Errorf("error getting process instances of %s: %v", appGuid, err)
+	}
+
+	for i, proc := range processes {
+		pInstances := instances[i]
 
+		var processStates []constant.ProcessInstanceState
+		var processSamples []utils.HealthSample
 		for _, pi := range pInstances {
-			statusMap[pi.State]++
-			processInstancesCount++
+			processStates = append(processStates, pi.State)
+			processSamples = append(processSamples, utils.HealthSample{
+				Health: utils.InstanceHealth(pi.State),
+				Age:    pi.Uptime,
+			})
+			processResources = append(processResources, instanceResource(proc, pi))
+		}
+		states = append(states, processStates...)
+		samples = append(samples, processSamples...)
+
+		processResource := processResource(appGuid, proc)
+		if policy != nil {
+			processResource.Health, processResource.HealthMessage = utils.InstancesHealthWithPolicy(policy, processSamples)
+		} else {
+			processResource.Health, processResource.HealthMessage = utils.InstancesHealth(processStates)
 		}
+		processResources = append(processResources, processResource)
 	}
 
-	if statusMap[constant.ProcessInstanceRunning] == processInstancesCount {
-		result.Health = proto.StatusReport_READY
-		result.HealthMessage = "all processes are reporting ready"
-	} else if statusMap[constant.ProcessInstanceCrashed] == processInstancesCount {
-		result.Health = proto.StatusReport_DOWN
-		result.HealthMessage = "all processes are crashed"
-	} else if statusMap[constant.ProcessInstanceStarting] == processInstancesCount {
-		result.Health = proto.StatusReport_ALIVE
-		result.HealthMessage = "all processes are starting"
-	} else if statusMap[constant.ProcessInstanceDown] == processInstancesCount {
-		result.Health = proto.StatusReport_DOWN
-		result.HealthMessage = "all processes are reporting down"
+	if policy != nil {
+		result.Health, result.HealthMessage = utils.InstancesHealthWithPolicy(policy, samples)
 	} else {
-		result.Health = proto.StatusReport_PARTIAL
-		result.HealthMessage = fmt.Sprintf(
-			"all processes are reporting mixed status: %v",
-			statusMap,
-		)
+		result.Health, result.HealthMessage = utils.InstancesHealth(states)
 	}
+
+	appResource := applicationResource(app)
+	appResource.Health = result.Health
+	appResource.HealthMessage = result.HealthMessage
+	result.Resources = append([]*proto.StatusReport_Resource{appResource}, processResources...)
 	return
 }
+
+func applicationResource(app resources.Application) *proto.StatusReport_Resource {
+	return &proto.StatusReport_Resource{
+		Id:                  app.GUID,
+		Name:                app.Name,
+		Type:                "application",
+		Platform:            platformName,
+		CategoryDisplayHint: proto.ResourceCategoryDisplayHint_INSTANCE_MANAGER,
+		StateJson: stateJson(map[string]interface{}{
+			"guid":  app.GUID,
+			"name":  app.Name,
+			"state": app.State,
+		}),
+	}
+}
+
+func processResource(appGuid string, process resources.Process) *proto.StatusReport_Resource {
+	return &proto.StatusReport_Resource{
+		Id:                  process.GUID,
+		ParentResourceId:    appGuid,
+		Name:                process.Type,
+		Type:                "process",
+		Platform:            platformName,
+		CategoryDisplayHint: proto.ResourceCategoryDisplayHint_INSTANCE_MANAGER,
+		StateJson: stateJson(map[string]interface{}{
+			"guid":              process.GUID,
+			"type":              process.Type,
+			"instances":         process.Instances.Value,
+			"memory_in_mb":      process.MemoryInMB.Value,
+			"disk_in_mb":        process.DiskInMB.Value,
+			"health_check_type": process.HealthCheckType,
+		}),
+	}
+}
+
+func instanceResource(process resources.Process, instance ccv3.ProcessInstance) *proto.StatusReport_Resource {
+	resource := &proto.StatusReport_Resource{
+		Id:                  fmt.Sprintf("%s/%d", process.GUID, instance.Index),
+		ParentResourceId:    process.GUID,
+		Name:                fmt.Sprintf("%s/%d", process.Type, instance.Index),
+		Type:                "instance",
+		Platform:            platformName,
+		CategoryDisplayHint: proto.ResourceCategoryDisplayHint_INSTANCE,
+		Health:              utils.InstanceHealth(instance.State),
+		HealthMessage:       fmt.Sprintf("instance is %s", instance.State),
+		StateJson: stateJson(map[string]interface{}{
+			"index":        instance.Index,
+			"state":        instance.State,
+			"uptime":       instance.Uptime.String(),
+			"cpu":          instance.CPU,
+			"memory_usage": instance.MemoryUsage,
+			"memory_quota": instance.MemoryQuota,
+			"disk_usage":   instance.DiskUsage,
+			"disk_quota":   instance.DiskQuota,
+			"details":      instance.Details,
+		}),
+	}
+
+	// Details contain the reason of a crash, or why the instance couldn't be placed
+	if instance.Details != "" {
+		resource.HealthMessage = fmt.Sprintf("%s: %s", resource.HealthMessage, instance.Details)
+	}
+	return resource
+}
+
+func stateJson(state map[string]interface{}) string {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,123 @@
+package cloudfoundry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/cli/util"
+	logcache "code.cloudfoundry.org/go-log-cache"
+	"code.cloudfoundry.org/go-log-cache/rpc/logcache_v1"
+)
+
+// containerMetricsWindow is how far back log-cache is searched for container metrics,
+// which are emitted by every instance every few seconds
+const containerMetricsWindow = 2 * time.Minute
+
+// ContainerMetrics is the latest resource usage of a process instance
+type ContainerMetrics struct {
+	ProcessType   string
+	InstanceIndex int
+	CPU           float64
+	Memory        uint64
+	MemoryQuota   uint64
+	Disk          uint64
+	DiskQuota     uint64
+}
+
+type tokenHTTPClient struct {
+	client      *http.Client
+	accessToken func() string
+}
+
+func (c *tokenHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", c.accessToken())
+	return c.client.Do(req)
+}
+
+func (c *Client) logCacheClient() (*logcache.Client, error) {
+	info, warns, err := c.client.RootResponse()
+	c.listWarnings(warns)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.LogCache() == "" {
+		return nil, fmt.Errorf("the Cloud Foundry API doesn't advertise a log-cache endpoint")
+	}
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: util.NewTLSConfig(nil, c.config.SkipSSLValidation()),
+		},
+		Timeout: c.config.DialTimeout() + 30*time.Second,
+	}
+
+	// The access token is refreshed by the Cloud Controller client and shared through the config
+	return logcache.NewClient(info.LogCache(), logcache.WithHTTPClient(&tokenHTTPClient{
+		client:      httpClient,
+		accessToken: c.tokens.AccessToken,
+	})), nil
+}
+
+// GetContainerMetrics returns the latest container metrics of each instance of the app
+func (c *Client) GetContainerMetrics(ctx context.Context, appGuid string) ([]ContainerMetrics, error) {
+	client, err := c.logCacheClient()
+	if err != nil {
+		return nil, err
+	}
+
+	envelopes, err := client.Read(ctx, appGuid, time.Now().Add(-containerMetricsWindow),
+		logcache.WithEnvelopeTypes(logcache_v1.EnvelopeType_GAUGE),
+		logcache.WithDescending(),
+		logcache.WithLimit(1000),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read container metrics from log-cache: %v", err)
+	}
+
+	var result []ContainerMetrics
+	seen := map[string]bool{}
+
+	// Envelopes are sorted from the newest, so the first one of each instance is the latest
+	for _, envelope := range envelopes {
+		metrics := envelope.GetGauge().GetMetrics()
+		if _, ok := metrics["memory_quota"]; !ok {
+			// Not a container metrics envelope
+			continue
+		}
+
+		processType := envelope.GetTags()["process_type"]
+		if processType == "" {
+			processType = "web"
+		}
+
+		key := processType + "/" + envelope.GetInstanceId()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		index, err := strconv.Atoi(envelope.GetInstanceId())
+		if err != nil {
+			c.logger.Debug("ignoring container metrics with invalid instance id",
+				"instance_id", envelope.GetInstanceId())
+			continue
+		}
+
+		result = append(result, ContainerMetrics{
+			ProcessType:   processType,
+			InstanceIndex: index,
+			CPU:           metrics["cpu"].GetValue(),
+			Memory:        uint64(metrics["memory"].GetValue()),
+			MemoryQuota:   uint64(metrics["memory_quota"].GetValue()),
+			Disk:          uint64(metrics["disk"].GetValue()),
+			DiskQuota:     uint64(metrics["disk_quota"].GetValue()),
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package cloudfoundry
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// NetworkPolicy allows the source app to reach the destination app over the
+// container-to-container network, on the given protocol and port range.
+type NetworkPolicy struct {
+	Source      NetworkPolicySource      `json:"source"`
+	Destination NetworkPolicyDestination `json:"destination"`
+}
+
+type NetworkPolicySource struct {
+	ID string `json:"id"`
+}
+
+type NetworkPolicyDestination struct {
+	ID       string             `json:"id"`
+	Protocol string             `json:"protocol"`
+	Ports    NetworkPolicyPorts `json:"ports"`
+}
+
+type NetworkPolicyPorts struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+type networkPolicies struct {
+	Policies []NetworkPolicy `json:"policies"`
+}
+
+// networkPolicyURL returns the base URL of the network policy API, as advertised by the
+// Cloud Controller root endpoint.
+func (c *Client) networkPolicyURL() (string, error) {
+	info, warns, err := c.client.RootResponse()
+	c.listWarnings(warns)
+	if err != nil {
+		return "", err
+	}
+
+	url := info.NetworkPolicyV1()
+	if url == "" {
+		return "", fmt.Errorf("the Cloud Foundry API doesn't advertise a network policy API")
+	}
+	return strings.TrimSuffix(url, "/"), nil
+}
+
+func (c *Client) CreateNetworkPolicies(policies []NetworkPolicy) error {
+	url, err := c.networkPolicyURL()
+	if err != nil {
+		return err
+	}
+	return c.requestURL(http.MethodPost, url+"/policies", networkPolicies{Policies: policies}, nil)
+}
+
+// GetNetworkPolicies returns the policies in which the app is either the source or the destination
+func (c *Client) GetNetworkPolicies(appGuid string) ([]NetworkPolicy, error) {
+	url, err := c.networkPolicyURL()
+	if err != nil {
+		return nil, err
+	}
+
+	var result networkPolicies
+	err = c.requestURL(http.MethodGet, fmt.Sprintf("%s/policies?id=%s", url, appGuid), nil, &result)
+	return result.Policies, err
+}
+
+func (c *Client) DeleteNetworkPolicies(policies []NetworkPolicy) error {
+	url, err := c.networkPolicyURL()
+	if err != nil {
+		return err
+	}
+	return c.requestURL(http.MethodPost, url+"/policies/delete", networkPolicies{Policies: policies}, nil)
+}
@@ -1,8 +1,12 @@
 package cloudfoundry
 
 import (
+	"fmt"
+	"net/http"
+
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
 	"code.cloudfoundry.org/cli/resources"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
 )
 
 func (c *Client) GetApplicationProcesses(appGuid string) (processes []resources.Process, err error) {
@@ -25,3 +29,69 @@ func (c *Client) UpdateApplicationProcess(p resources.Process) (proc resources.P
 	c.listWarnings(warns)
 	return proc, err
 }
+
+// ApplySpaceManifest applies a manifest to the apps of a space, and waits until it is applied
+func (c *Client) ApplySpaceManifest(spaceGuid string, manifest []byte) error {
+	jobUrl, warns, err := c.client.UpdateSpaceApplyManifest(spaceGuid, manifest)
+	c.listWarnings(warns)
+	if err != nil {
+		return err
+	}
+	return c.pollJob(jobUrl)
+}
+
+// ReadinessHealthCheck is the readiness health check of a process, which isn't covered by the ccv3 client
+type ReadinessHealthCheck struct {
+	Type string                   `json:"type"`
+	Data ReadinessHealthCheckData `json:"data"`
+}
+
+type ReadinessHealthCheckData struct {
+	Endpoint          string `json:"endpoint,omitempty"`
+	InvocationTimeout int64  `json:"invocation_timeout,omitempty"`
+	Interval          int64  `json:"interval,omitempty"`
+}
+
+func (c *Client) UpdateProcessReadinessHealthCheck(processGuid string, check ReadinessHealthCheck) error {
+	return c.request(http.MethodPatch, fmt.Sprintf("/v3/processes/%s", processGuid), map[string]interface{}{
+		"readiness_health_check": check,
+	}, nil)
+}
+
+// GetProcessInstancesRoutable tells which instances of a process pass their readiness check. Instances
+// are missing from the result if the Cloud Controller doesn't report their readiness.
+func (c *Client) GetProcessInstancesRoutable(processGuid string) (map[int64]bool, error) {
+	var stats struct {
+		Resources []struct {
+			Index    int64 `json:"index"`
+			Routable *bool `json:"routable"`
+		} `json:"resources"`
+	}
+	err := c.request(http.MethodGet, fmt.Sprintf("/v3/processes/%s/stats", processGuid), nil, &stats)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[int64]bool{}
+	for _, instance := range stats.Resources {
+		if instance.Routable != nil {
+			result[instance.Index] = *instance.Routable
+		}
+	}
+	return result, nil
+}
+
+// GetProcessesRoutable is like GetProcessInstancesRoutable for several processes, fetched concurrently
+func (c *Client) GetProcessesRoutable(processes []resources.Process) ([]map[int64]bool, error) {
+	result := make([]map[int64]bool, len(processes))
+	err := utils.ForEachConcurrently(len(processes), maxConcurrentRequests, func(i int) error {
+		routable, err := c.GetProcessInstancesRoutable(processes[i].GUID)
+		if err != nil {
+			return fmt.Errorf("error getting readiness of process %s (%s): %v",
+				processes[i].Type, processes[i].GUID, err)
+		}
+		result[i] = routable
+		return nil
+	})
+	return result, err
+}
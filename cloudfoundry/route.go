@@ -1,11 +1,28 @@
 package cloudfoundry
 
 import (
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccerror"
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
 	"code.cloudfoundry.org/cli/resources"
 	"fmt"
+	"net/http"
 )
 
+// RouteDestination is an app destination to be mapped to a route
+type RouteDestination struct {
+	AppGUID string
+	// Port is the app port receiving the traffic, the default app port is used if 0
+	Port int
+	// Protocol is either http1 or http2 for HTTP routes, the default (http1) is used if empty
+	Protocol string
+}
+
+// RouteOptions are the per-route settings of the gorouter
+type RouteOptions struct {
+	// LoadBalancing is either round-robin or least-connection
+	LoadBalancing string `json:"loadbalancing,omitempty"`
+}
+
 func (c *Client) GetRoute(guid string) (route resources.Route, err error) {
 	routes, warns, err := c.client.GetRoutes(
 		ccv3.Query{
@@ -17,6 +34,9 @@ func (c *Client) GetRoute(guid string) (route resources.Route, err error) {
 	if err != nil {
 		return route, err
 	}
+	if len(routes) == 0 {
+		return route, ccerror.ResourceNotFoundError{Message: fmt.Sprintf("route %s not found", guid)}
+	}
 	if len(routes) != 1 {
 		return route, fmt.Errorf("expected 1 route, got %d routes instead", len(routes))
 	}
@@ -24,43 +44,51 @@ func (c *Client) GetRoute(guid string) (route resources.Route, err error) {
 	return route, nil
 }
 
-func (c *Client) UpsertRoute(
-	hostname string,
-	domain resources.Domain,
-	spaceGuid string,
-) (route resources.Route, err error) {
-	routes, _, err := c.client.GetRoutes(ccv3.Query{
-		Key:    ccv3.DomainGUIDFilter,
-		Values: []string{domain.GUID},
-	}, ccv3.Query{
-		Key:    ccv3.HostsFilter,
-		Values: []string{hostname},
-	})
+// UpsertRoute returns the route matching the domain, host, path and port of the
+// given route. If no such route exists, it is created in the given space.
+func (c *Client) UpsertRoute(route resources.Route) (resources.Route, error) {
+	var query []ccv3.Query
+	query = filterQuery(query, ccv3.DomainGUIDFilter, route.DomainGUID)
+	if route.Host != "" {
+		query = filterQuery(query, ccv3.HostsFilter, route.Host)
+	}
+	if route.Path != "" {
+		query = filterQuery(query, ccv3.PathsFilter, route.Path)
+	}
+	if route.Port != 0 {
+		query = filterQuery(query, ccv3.PortsFilter, fmt.Sprintf("%d", route.Port))
+	}
 
+	routes, warns, err := c.client.GetRoutes(query...)
+	c.listWarnings(warns)
 	if err != nil {
 		return route, err
 	}
 
-	if len(routes) > 1 {
-		return route, fmt.Errorf("more than one route returned")
+	// The filters above don't exclude routes with a path or a port for the same
+	// host, so we need to match them exactly
+	var matching []resources.Route
+	for _, r := range routes {
+		if r.Host == route.Host && r.Path == route.Path && (route.Port == 0 || r.Port == route.Port) {
+			matching = append(matching, r)
+		}
 	}
 
-	if len(routes) == 1 {
-		route = routes[0]
-		return route, nil
+	if len(matching) > 1 {
+		return route, fmt.Errorf("more than one route returned")
 	}
 
-	route, err = c.CreateRoute(resources.Route{
-		DomainGUID: domain.GUID,
-		SpaceGUID:  spaceGuid,
-		Host:       hostname,
-	})
-
-	if err != nil {
-		return route, err
+	if len(matching) == 1 {
+		return matching[0], nil
 	}
 
-	return route, nil
+	return c.CreateRoute(resources.Route{
+		DomainGUID: route.DomainGUID,
+		SpaceGUID:  route.SpaceGUID,
+		Host:       route.Host,
+		Path:       route.Path,
+		Port:       route.Port,
+	})
 }
 
 func (c *Client) MapRoute(routeGuid string, appGuid string) error {
@@ -69,6 +97,39 @@ func (c *Client) MapRoute(routeGuid string, appGuid string) error {
 	return err
 }
 
+// MapRouteDestination maps the route to the given destination. Unlike MapRoute, it
+// allows to choose the app port the traffic is sent to.
+func (c *Client) MapRouteDestination(routeGuid string, destination RouteDestination) error {
+	type destinationApp struct {
+		GUID string `json:"guid"`
+	}
+	type routeDestination struct {
+		App      destinationApp `json:"app"`
+		Port     int            `json:"port,omitempty"`
+		Protocol string         `json:"protocol,omitempty"`
+	}
+
+	body := struct {
+		Destinations []routeDestination `json:"destinations"`
+	}{
+		Destinations: []routeDestination{{
+			App:      destinationApp{GUID: destination.AppGUID},
+			Port:     destination.Port,
+			Protocol: destination.Protocol,
+		}},
+	}
+
+	return c.request(http.MethodPost, fmt.Sprintf("/v3/routes/%s/destinations", routeGuid), body, nil)
+}
+
+func (c *Client) UpdateRouteOptions(routeGuid string, options RouteOptions) error {
+	body := struct {
+		Options RouteOptions `json:"options"`
+	}{Options: options}
+
+	return c.request(http.MethodPatch, fmt.Sprintf("/v3/routes/%s", routeGuid), body, nil)
+}
+
 func (c *Client) UnmapRoute(routeGuid string, appGuid string) error {
 	warns, err := c.client.UnmapRoute(routeGuid, appGuid)
 	c.listWarnings(warns)
@@ -91,4 +152,18 @@ func (c *Client) GetApplicationRoutes(guid string) ([]resources.Route, error) {
 	routes, warns, err := c.client.GetApplicationRoutes(guid)
 	c.listWarnings(warns)
 	return routes, err
-}
\ No newline at end of file
+}
+
+// IsTCPDomain returns true if routes on the domain are TCP routes, which are
+// identified by a port instead of a hostname
+func IsTCPDomain(domain resources.Domain) bool {
+	if domain.RouterGroup != "" {
+		return true
+	}
+	for _, protocol := range domain.Protocols {
+		if protocol == "tcp" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package cloudfoundry
+
+import (
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
+	"code.cloudfoundry.org/cli/resources"
+)
+
+// GetRouteBindings returns the route service bindings of a route. If serviceInstanceGuid
+// is not empty, only the bindings to that service instance are returned.
+func (c *Client) GetRouteBindings(routeGuid string, serviceInstanceGuid string) ([]resources.RouteBinding, error) {
+	var query []ccv3.Query
+	query = filterQuery(query, ccv3.RouteGUIDFilter, routeGuid)
+	if serviceInstanceGuid != "" {
+		query = filterQuery(query, ccv3.ServiceInstanceGUIDFilter, serviceInstanceGuid)
+	}
+
+	bindings, _, warns, err := c.client.GetRouteBindings(query...)
+	c.listWarnings(warns)
+	return bindings, err
+}
+
+// CreateRouteBinding binds the route service to the route and waits for the binding
+// to be completed, since the bindings of managed services are asynchronous
+func (c *Client) CreateRouteBinding(routeGuid string, serviceInstanceGuid string) error {
+	jobUrl, warns, err := c.client.CreateRouteBinding(resources.RouteBinding{
+		RouteGUID:           routeGuid,
+		ServiceInstanceGUID: serviceInstanceGuid,
+	})
+	c.listWarnings(warns)
+	if err != nil {
+		return err
+	}
+	return c.pollJob(jobUrl)
+}
+
+func (c *Client) DeleteRouteBinding(guid string) error {
+	jobUrl, warns, err := c.client.DeleteRouteBinding(guid)
+	c.listWarnings(warns)
+	if err != nil {
+		return err
+	}
+	return c.pollJob(jobUrl)
+}
+
+func (c *Client) pollJob(jobUrl ccv3.JobURL) error {
+	if jobUrl == "" {
+		return nil
+	}
+	warns, err := c.client.PollJob(jobUrl)
+	c.listWarnings(warns)
+	return err
+}
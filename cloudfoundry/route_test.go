@@ -0,0 +1,23 @@
+package cloudfoundry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccerror"
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry/cftest"
+)
+
+func TestGetRouteNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(cftest.List())
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(t, server).GetRoute("route-guid")
+	assert.IsType(t, ccerror.ResourceNotFoundError{}, err)
+}
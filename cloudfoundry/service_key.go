@@ -0,0 +1,56 @@
+package cloudfoundry
+
+import (
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
+	"code.cloudfoundry.org/cli/resources"
+)
+
+// GetServiceKeyCredentials returns the credentials of a service key of a managed service instance.
+// If keyName is empty, the first key of the service instance is used.
+func (c *Client) GetServiceKeyCredentials(serviceInstanceGuid string, keyName string) (map[string]interface{}, error) {
+	query := []ccv3.Query{
+		{Key: ccv3.ServiceInstanceGUIDFilter, Values: []string{serviceInstanceGuid}},
+		{Key: ccv3.TypeFilter, Values: []string{string(resources.KeyBinding)}},
+	}
+	if keyName != "" {
+		query = filterQuery(query, ccv3.NameFilter, keyName)
+	}
+
+	keys, warns, err := c.client.GetServiceCredentialBindings(query...)
+	c.listWarnings(warns)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		if keyName != "" {
+			return nil, fmt.Errorf("service key %s not found", keyName)
+		}
+		return nil, fmt.Errorf("the service instance has no service key")
+	}
+
+	details, warns, err := c.client.GetServiceCredentialBindingDetails(keys[0].GUID)
+	c.listWarnings(warns)
+	if err != nil {
+		return nil, err
+	}
+	return details.Credentials, nil
+}
+
+// GetUserProvidedServiceCredentials returns the credentials of a user-provided service instance
+func (c *Client) GetUserProvidedServiceCredentials(serviceInstanceGuid string) (map[string]interface{}, error) {
+	var credentials map[string]interface{}
+	err := c.request(http.MethodGet, fmt.Sprintf("/v3/service_instances/%s/credentials", serviceInstanceGuid), nil, &credentials)
+	return credentials, err
+}
+
+// GetServiceCredentials returns the credentials of a service instance: the ones of a user-provided service,
+// or the ones of a service key of a managed service (the first key if keyName is empty)
+func (c *Client) GetServiceCredentials(serviceInstance resources.ServiceInstance, keyName string) (map[string]interface{}, error) {
+	if serviceInstance.Type == resources.UserProvidedServiceInstance {
+		return c.GetUserProvidedServiceCredentials(serviceInstance.GUID)
+	}
+	return c.GetServiceKeyCredentials(serviceInstance.GUID, keyName)
+}
@@ -0,0 +1,127 @@
+package cloudfoundry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller"
+	ccWrapper "code.cloudfoundry.org/cli/api/cloudcontroller/wrapper"
+	"code.cloudfoundry.org/cli/api/uaa"
+)
+
+// accessTokenExpirationMargin is the remaining validity below which an access token is refreshed
+const accessTokenExpirationMargin = time.Minute
+
+// tokenCache guards the tokens of the CF config, which are read and refreshed by concurrent requests.
+// It is the token cache of the authentication wrappers, and the UAA client of the UAA one.
+type tokenCache struct {
+	mu    sync.Mutex
+	cache ccWrapper.TokenCache
+	// refreshMu serializes the refreshes, client does the actual refresh
+	refreshMu sync.Mutex
+	client    ccWrapper.UAAClient
+}
+
+func (t *tokenCache) AccessToken() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cache.AccessToken()
+}
+
+func (t *tokenCache) RefreshToken() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cache.RefreshToken()
+}
+
+func (t *tokenCache) SetAccessToken(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cache.SetAccessToken(token)
+}
+
+func (t *tokenCache) SetRefreshToken(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cache.SetRefreshToken(token)
+}
+
+// RefreshAccessToken refreshes the tokens for the UAA authentication wrapper, once UAA rejected them
+func (t *tokenCache) RefreshAccessToken(refreshToken string) (uaa.RefreshedTokens, error) {
+	t.refreshMu.Lock()
+	defer t.refreshMu.Unlock()
+	return t.refreshLocked(refreshToken)
+}
+
+// refresh refreshes the tokens, unless the access token is no longer the expired one seen by the caller:
+// the requests finding the same expired token at the same time share a single refresh. Unlike comparing
+// refresh tokens, this works whether or not UAA issues a new refresh token with each refresh.
+func (t *tokenCache) refresh(seenAccessToken string) error {
+	t.refreshMu.Lock()
+	defer t.refreshMu.Unlock()
+
+	if t.AccessToken() != seenAccessToken {
+		return nil
+	}
+	_, err := t.refreshLocked(t.RefreshToken())
+	return err
+}
+
+func (t *tokenCache) refreshLocked(refreshToken string) (uaa.RefreshedTokens, error) {
+	tokens, err := t.client.RefreshAccessToken(refreshToken)
+	if err != nil {
+		return tokens, err
+	}
+	t.SetAccessToken(tokens.AuthorizationToken())
+	t.SetRefreshToken(tokens.RefreshToken)
+	return tokens, nil
+}
+
+// tokenAuthentication adds the access token to the Cloud Controller requests, refreshing it first if it's
+// about to expire. It replaces the wrapper of the CF CLI, which refreshes it once per concurrent request.
+type tokenAuthentication struct {
+	connection cloudcontroller.Connection
+	tokens     *tokenCache
+}
+
+func (a *tokenAuthentication) Make(request *cloudcontroller.Request, passedResponse *cloudcontroller.Response) error {
+	if request.Header.Get("Authorization") == "" && (a.tokens.AccessToken() != "" || a.tokens.RefreshToken() != "") {
+		accessToken := a.tokens.AccessToken()
+		if expiresSoon(accessToken) {
+			if err := a.tokens.refresh(accessToken); err != nil {
+				return err
+			}
+			accessToken = a.tokens.AccessToken()
+		}
+		request.Header.Set("Authorization", accessToken)
+	}
+	return a.connection.Make(request, passedResponse)
+}
+
+func (a *tokenAuthentication) Wrap(innerconnection cloudcontroller.Connection) cloudcontroller.Connection {
+	a.connection = innerconnection
+	return a
+}
+
+// expiresSoon tells whether a JWT access token expires within accessTokenExpirationMargin. Tokens which
+// can't be parsed are considered expired, so that they are refreshed.
+func expiresSoon(accessToken string) bool {
+	parts := strings.Split(strings.TrimPrefix(accessToken, "bearer "), ".")
+	if len(parts) != 3 {
+		return true
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return true
+	}
+	var claims struct {
+		Expiration int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Expiration == 0 {
+		return true
+	}
+	return time.Until(time.Unix(claims.Expiration, 0)) < accessTokenExpirationMargin
+}
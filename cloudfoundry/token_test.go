@@ -0,0 +1,114 @@
+package cloudfoundry
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller"
+	"code.cloudfoundry.org/cli/api/uaa"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTokenStore struct {
+	accessToken  string
+	refreshToken string
+}
+
+func (s *fakeTokenStore) AccessToken() string          { return s.accessToken }
+func (s *fakeTokenStore) RefreshToken() string         { return s.refreshToken }
+func (s *fakeTokenStore) SetAccessToken(token string)  { s.accessToken = token }
+func (s *fakeTokenStore) SetRefreshToken(token string) { s.refreshToken = token }
+
+// fakeRefresher returns a new access token valid for an hour, and the refresh token it's
+// given unless rotate is set. Refreshing takes a while, so that concurrent requests overlap.
+type fakeRefresher struct {
+	rotate bool
+	calls  int32
+}
+
+func (r *fakeRefresher) RefreshAccessToken(refreshToken string) (uaa.RefreshedTokens, error) {
+	calls := atomic.AddInt32(&r.calls, 1)
+	time.Sleep(50 * time.Millisecond)
+	if r.rotate {
+		refreshToken = fmt.Sprintf("refresh-%d", calls)
+	}
+	return uaa.RefreshedTokens{AccessToken: jwt(time.Hour), RefreshToken: refreshToken, Type: "bearer"}, nil
+}
+
+// fakeConnection records the Authorization header of the requests
+type fakeConnection struct {
+	mu             sync.Mutex
+	authorizations []string
+}
+
+func (c *fakeConnection) Make(request *cloudcontroller.Request, _ *cloudcontroller.Response) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.authorizations = append(c.authorizations, request.Header.Get("Authorization"))
+	return nil
+}
+
+// jwt returns an unsigned JWT expiring after validity
+func jwt(validity time.Duration) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return fmt.Sprintf("%s.%s.%s",
+		encode([]byte(`{"alg":"none"}`)),
+		encode([]byte(fmt.Sprintf(`{"exp":%d}`, time.Now().Add(validity).Unix()))),
+		encode([]byte("signature")),
+	)
+}
+
+func TestTokenAuthenticationRefreshesOnce(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		rotate bool
+	}{
+		{name: "fixed refresh token"},
+		{name: "rotated refresh token", rotate: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			refresher := &fakeRefresher{rotate: tc.rotate}
+			tokens := &tokenCache{
+				cache:  &fakeTokenStore{accessToken: "bearer " + jwt(-time.Minute), refreshToken: "refresh"},
+				client: refresher,
+			}
+			connection := &fakeConnection{}
+			auth := (&tokenAuthentication{tokens: tokens}).Wrap(connection)
+
+			var wg sync.WaitGroup
+			start := make(chan struct{})
+			for i := 0; i < 16; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					request, _ := http.NewRequest(http.MethodGet, "https://api.example.com/v3/apps", nil)
+					err := auth.Make(cloudcontroller.NewRequest(request, nil), &cloudcontroller.Response{})
+					assert.NoError(t, err)
+				}()
+			}
+			close(start)
+			wg.Wait()
+
+			// The requests finding the expired token at the same time share the refreshed one
+			assert.Equal(t, int32(1), refresher.calls)
+			assert.Len(t, connection.authorizations, 16)
+			for _, authorization := range connection.authorizations {
+				assert.Equal(t, tokens.AccessToken(), authorization)
+			}
+			assert.False(t, expiresSoon(tokens.AccessToken()))
+		})
+	}
+}
+
+func TestExpiresSoon(t *testing.T) {
+	assert.False(t, expiresSoon("bearer "+jwt(time.Hour)))
+	assert.True(t, expiresSoon("bearer "+jwt(30*time.Second)))
+	assert.True(t, expiresSoon("bearer "+jwt(-time.Hour)))
+	assert.True(t, expiresSoon("bearer not-a-jwt"))
+}
@@ -0,0 +1,170 @@
+package configsourcer
+
+import (
+	"context"
+	"fmt"
+
+	"code.cloudfoundry.org/cli/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	proto "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SourceConfig is the configuration of the config source, set with `waypoint config source-set`
+type SourceConfig struct {
+	Organisation string `hcl:"organisation,optional"`
+	Space        string `hcl:"space,optional"`
+}
+
+// reqConfig is the configuration of a dynamic variable, e.g. dynamic("cloudfoundry", {service = "db", key = "uri"})
+type reqConfig struct {
+	// Service is the name of the service instance
+	Service string
+	// Key selects the value in the credentials, nested values are selected with a dot-separated path
+	Key string
+	// ServiceKey is the name of the service key of a managed service, the first key is used if empty
+	ServiceKey string
+	// Organisation and Space override the ones of the source configuration
+	Organisation string
+	Space        string
+}
+
+func parseReqConfig(config map[string]string) (reqConfig, error) {
+	result := reqConfig{
+		Service:      config["service"],
+		Key:          config["key"],
+		ServiceKey:   config["service_key"],
+		Organisation: config["organisation"],
+		Space:        config["space"],
+	}
+	if result.Service == "" {
+		return result, fmt.Errorf("service is required")
+	}
+	if result.Key == "" {
+		return result, fmt.Errorf("key is required")
+	}
+	return result, nil
+}
+
+// ConfigSourcer reads dynamic configuration from the credentials of Cloud Foundry service keys
+// and user-provided services
+type ConfigSourcer struct {
+	config SourceConfig
+}
+
+// Config implements Configurable
+func (cs *ConfigSourcer) Config() (interface{}, error) {
+	return &cs.config, nil
+}
+
+// ReadFunc implements component.ConfigSourcer
+func (cs *ConfigSourcer) ReadFunc() interface{} {
+	return cs.read
+}
+
+// StopFunc implements component.ConfigSourcer
+func (cs *ConfigSourcer) StopFunc() interface{} {
+	return cs.stop
+}
+
+func (cs *ConfigSourcer) read(
+	ctx context.Context,
+	log hclog.Logger,
+	reqs []*component.ConfigRequest,
+) ([]*proto.ConfigSource_Value, error) {
+	// The credentials are masked in the logs, including the warnings of the Cloud Foundry client
+	redactor := utils.NewRedactor()
+	log = utils.NewRedactingLogger(log, redactor)
+
+	client, err := cloudfoundry.New(log)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Cloud Foundry client: %v", err)
+	}
+
+	// Several variables usually come from the same service, whose credentials are only fetched once
+	spaces := map[string]resources.Space{}
+	credentials := map[string]map[string]interface{}{}
+
+	var results []*proto.ConfigSource_Value
+	for _, req := range reqs {
+		result := &proto.ConfigSource_Value{Name: req.Name}
+		results = append(results, result)
+
+		value, err := cs.readValue(client, redactor, spaces, credentials, req)
+		if err != nil {
+			log.Warn("unable to read configuration", "name", req.Name, "error", err)
+			result.Result = &proto.ConfigSource_Value_Error{
+				Error: status.New(codes.Aborted, err.Error()).Proto(),
+			}
+			continue
+		}
+		result.Result = &proto.ConfigSource_Value_Value{Value: value}
+	}
+
+	return results, nil
+}
+
+func (cs *ConfigSourcer) readValue(
+	client *cloudfoundry.Client,
+	redactor *utils.Redactor,
+	spaces map[string]resources.Space,
+	credentials map[string]map[string]interface{},
+	req *component.ConfigRequest,
+) (string, error) {
+	config, err := parseReqConfig(req.Config)
+	if err != nil {
+		return "", err
+	}
+
+	org, spaceName := cs.config.Organisation, cs.config.Space
+	if config.Organisation != "" {
+		org = config.Organisation
+	}
+	if config.Space != "" {
+		spaceName = config.Space
+	}
+	if org == "" || spaceName == "" {
+		return "", fmt.Errorf("organisation and space are required, in the source or in the variable configuration")
+	}
+
+	spaceKey := org + "/" + spaceName
+	space, ok := spaces[spaceKey]
+	if !ok {
+		_, space, err = client.SelectOrgAndSpace(org, spaceName)
+		if err != nil {
+			return "", err
+		}
+		spaces[spaceKey] = space
+	}
+
+	credentialsKey := fmt.Sprintf("%s/%s/%s", space.GUID, config.Service, config.ServiceKey)
+	creds, ok := credentials[credentialsKey]
+	if !ok {
+		serviceInstance, err := client.GetServiceInstances(space.GUID, config.Service)
+		if err != nil {
+			return "", fmt.Errorf("unable to find service %s in %s: %v", config.Service, spaceKey, err)
+		}
+
+		creds, err = client.GetServiceCredentials(serviceInstance, config.ServiceKey)
+		if err != nil {
+			return "", fmt.Errorf("unable to get credentials of service %s: %v", config.Service, err)
+		}
+		redactor.AddCredentials(creds)
+		credentials[credentialsKey] = creds
+	}
+
+	return utils.CredentialValue(creds, config.Key)
+}
+
+func (cs *ConfigSourcer) stop() error {
+	return nil
+}
+
+var (
+	_ component.ConfigSourcer = (*ConfigSourcer)(nil)
+	_ component.Configurable  = (*ConfigSourcer)(nil)
+)
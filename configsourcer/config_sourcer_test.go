@@ -0,0 +1,24 @@
+package configsourcer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReqConfig(t *testing.T) {
+	config, err := parseReqConfig(map[string]string{
+		"service":     "db",
+		"key":         "uri",
+		"service_key": "waypoint",
+		"space":       "production",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, reqConfig{Service: "db", Key: "uri", ServiceKey: "waypoint", Space: "production"}, config)
+
+	_, err = parseReqConfig(map[string]string{"key": "uri"})
+	assert.EqualError(t, err, "service is required")
+
+	_, err = parseReqConfig(map[string]string{"service": "db"})
+	assert.EqualError(t, err, "key is required")
+}
@@ -4,12 +4,17 @@ go 1.17
 
 require (
 	code.cloudfoundry.org/cli v0.0.0-20220604004407-0ad1d6398d49
+	code.cloudfoundry.org/go-log-cache v1.0.1-0.20211011162012-ede82a99d3cc
+	github.com/fatih/color v1.12.0
 	github.com/google/uuid v1.2.0
+	github.com/hashicorp/go-argmapper v0.2.4
 	github.com/hashicorp/go-hclog v0.16.1
 	github.com/hashicorp/waypoint v0.10.1
 	github.com/hashicorp/waypoint-plugin-sdk v0.0.0-20220916144417-dbf0e8e09cc7
 	github.com/stretchr/testify v1.7.1
+	google.golang.org/grpc v1.45.0
 	google.golang.org/protobuf v1.27.1
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 	k8s.io/apimachinery v0.22.2
 )
 
@@ -17,7 +22,6 @@ require (
 	cloud.google.com/go v0.81.0 // indirect
 	code.cloudfoundry.org/bytefmt v0.0.0-20190710193110-1eb035ffe2b6 // indirect
 	code.cloudfoundry.org/cli-plugin-repo v0.0.0-20200304195157-af98c4be9b85 // indirect
-	code.cloudfoundry.org/go-log-cache v1.0.1-0.20211011162012-ede82a99d3cc // indirect
 	code.cloudfoundry.org/go-loggregator/v8 v8.0.5 // indirect
 	code.cloudfoundry.org/gofileutils v0.0.0-20170111115228-4d0c80011a0f // indirect
 	code.cloudfoundry.org/jsonry v1.1.3 // indirect
@@ -61,7 +65,6 @@ require (
 	github.com/docker/go-connections v0.4.0 // indirect
 	github.com/docker/go-metrics v0.0.1 // indirect
 	github.com/docker/go-units v0.4.0 // indirect
-	github.com/fatih/color v1.12.0 // indirect
 	github.com/form3tech-oss/jwt-go v3.2.3+incompatible // indirect
 	github.com/go-logr/logr v0.4.0 // indirect
 	github.com/go-test/deep v1.0.4 // indirect
@@ -78,7 +81,6 @@ require (
 	github.com/grpc-ecosystem/grpc-gateway/v2 v2.10.0 // indirect
 	github.com/grpc-ecosystem/grpc-opentracing v0.0.0-20180507213350-8e809c8a8645 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
-	github.com/hashicorp/go-argmapper v0.2.4 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
 	github.com/hashicorp/go-plugin v1.4.2 // indirect
 	github.com/hashicorp/hcl/v2 v2.10.1-0.20210621220818-327f3ce2570e // indirect
@@ -142,12 +144,10 @@ require (
 	golang.org/x/time v0.0.0-20210723032227-1f47c861a9ac // indirect
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/genproto v0.0.0-20220317150908-0efb43f6373e // indirect
-	google.golang.org/grpc v1.45.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/cheggaaa/pb.v1 v1.0.28 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 	k8s.io/client-go v0.22.2 // indirect
 	k8s.io/klog/v2 v2.9.0 // indirect
 	k8s.io/utils v0.0.0-20210820185131-d34e5cb4466e // indirect
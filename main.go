@@ -2,6 +2,7 @@ package main
 
 import (
 	sdk "github.com/hashicorp/waypoint-plugin-sdk"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/configsourcer"
 	"github.com/swisscom/waypoint-plugin-cloudfoundry/platform"
 	"github.com/swisscom/waypoint-plugin-cloudfoundry/release"
 )
@@ -18,5 +19,6 @@ func main() {
 		// &registry.Registry{},
 		&platform.Platform{},
 		&release.Releaser{},
-	))
+		&configsourcer.ConfigSourcer{},
+	), sdk.WithMappers(platform.Mappers...))
 }
@@ -0,0 +1,84 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+// Environment variables used by the CredHub CLI, used when the credhub block doesn't set the same options
+const (
+	credHubServerEnv = "CREDHUB_SERVER"
+	credHubClientEnv = "CREDHUB_CLIENT"
+	credHubSecretEnv = "CREDHUB_SECRET"
+)
+
+// credHubClient returns a client of the CredHub API configured with the credhub block,
+// or with the environment of the runner
+func (p *Platform) credHubClient(client *cloudfoundry.Client) (*cloudfoundry.CredHubClient, error) {
+	config := CredHubConfig{}
+	if p.config.CredHub != nil {
+		config = *p.config.CredHub
+	}
+	if config.URL == "" {
+		config.URL = os.Getenv(credHubServerEnv)
+	}
+	if config.ClientID == "" {
+		config.ClientID = os.Getenv(credHubClientEnv)
+		config.ClientSecret = os.Getenv(credHubSecretEnv)
+	}
+
+	if config.URL == "" {
+		return nil, fmt.Errorf("the CredHub URL has to be set with credhub.url or %s", credHubServerEnv)
+	}
+	p.redactor.AddExplicit(config.ClientSecret)
+	return client.NewCredHubClient(config.URL, config.ClientID, config.ClientSecret), nil
+}
+
+// resolveCredHubReferences replaces the env values referencing CredHub credentials with their value
+func (p *Platform) resolveCredHubReferences(state *DeploymentState, env map[string]string) error {
+	var keys []string
+	for k, v := range env {
+		if _, _, ok := utils.ParseCredHubReference(v); ok {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+
+	step := (*state.sg).Add("Resolving CredHub references")
+	credHub, err := p.credHubClient(state.client)
+	if err != nil {
+		step.Abort()
+		return err
+	}
+
+	credentials := map[string]interface{}{}
+	for _, k := range keys {
+		name, key, _ := utils.ParseCredHubReference(env[k])
+		credential, ok := credentials[name]
+		if !ok {
+			credential, err = credHub.GetValue(name)
+			if err != nil {
+				step.Abort()
+				return fmt.Errorf("unable to resolve %s: %v", k, err)
+			}
+			credentials[name] = credential
+		}
+
+		env[k], err = utils.CredHubValue(credential, key)
+		if err != nil {
+			step.Abort()
+			return fmt.Errorf("unable to resolve %s from CredHub credential %s: %v", k, name, err)
+		}
+		p.redactor.AddExplicit(env[k])
+	}
+
+	step.Done()
+	return nil
+}
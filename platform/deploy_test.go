@@ -2,11 +2,22 @@ package platform
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-argmapper"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/waypoint-plugin-sdk/component"
 	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/hashicorp/waypoint/builtin/aws/ecr"
 	"github.com/hashicorp/waypoint/builtin/docker"
+	"github.com/hashicorp/waypoint/builtin/pack"
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry/cftest"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -52,3 +63,257 @@ func TestDeploy(t *testing.T) {
 		t.Fatalf("expected deployment.Name to be app-name, but got %s instead", deployment.Name)
 	}
 }
+
+func TestDeployPinsImageDigest(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/team/app/manifests/1.0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	defer registry.Close()
+	image := strings.TrimPrefix(registry.URL, "http://") + "/team/app"
+
+	var packageRequest []byte
+	server := fakeCloudController(t, false, func(route string, body []byte) {
+		if route == "POST /v3/packages" {
+			packageRequest = body
+		}
+	})
+	defer server.Close()
+	cftest.Target(t, server, fakeAccessToken, fakeRefreshToken)
+	t.Setenv(dockerConfigEnv, t.TempDir())
+
+	p := Platform{config: Config{
+		Organisation:   "org",
+		Space:          "space",
+		Domain:         "example.com",
+		Docker:         &DockerConfig{Insecure: true},
+		PinImageDigest: true,
+	}}
+
+	deployment, err := p.Deploy(
+		context.Background(),
+		hclog.NewNullLogger(),
+		&component.Source{App: "app"},
+		&docker.Image{Image: image, Tag: "1.0"},
+		&component.DeploymentConfig{},
+		terminal.NonInteractiveUI(context.Background()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, digest, deployment.ImageDigest)
+	assert.Contains(t, string(packageRequest), fmt.Sprintf(`"image":"%s@%s"`, image, digest))
+}
+
+// TestDeployFuncArguments checks that Waypoint can call the deploy function with the values it provides
+// when deploying the artifact of the docker registry plugin
+func TestDeployFuncArguments(t *testing.T) {
+	server := fakeCloudController(t, false, nil)
+	defer server.Close()
+	cftest.Target(t, server, fakeAccessToken, fakeRefreshToken)
+	t.Setenv(dockerConfigEnv, t.TempDir())
+
+	p := Platform{config: Config{
+		Organisation: "org",
+		Space:        "space",
+		Domain:       "example.com",
+	}}
+
+	f, err := argmapper.NewFunc(p.DeployFunc())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ui terminal.UI = terminal.NonInteractiveUI(context.Background())
+	result := f.Call(
+		argmapper.Typed(context.Background()),
+		argmapper.Typed(hclog.NewNullLogger()),
+		argmapper.Typed(&component.Source{App: "app"}),
+		argmapper.Typed(&docker.Image{Image: "registry.example.com/app", Tag: "1.0"}),
+		argmapper.Typed(&component.DeploymentConfig{}),
+		argmapper.Typed(ui),
+	)
+	if err := result.Err(); err != nil {
+		t.Fatal(err)
+	}
+	assert.IsType(t, &Deployment{}, result.Out(0))
+}
+
+// TestDeployFuncMappedArtifacts checks that the artifacts of other plugins are deployed through the mappers
+// registered with the plugin, the way Waypoint calls the deploy function
+func TestDeployFuncMappedArtifacts(t *testing.T) {
+	var mappers []*argmapper.Func
+	for _, mapper := range Mappers {
+		f, err := argmapper.NewFunc(mapper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mappers = append(mappers, f)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		artifact interface{}
+		image    string
+		err      string
+	}{
+		{
+			name:     "ecr",
+			artifact: &ecr.Image{Image: "123456789012.dkr.ecr.eu-central-1.amazonaws.com/app", Tag: "1.0"},
+			image:    "123456789012.dkr.ecr.eu-central-1.amazonaws.com/app:1.0",
+		},
+		{
+			name:     "pack",
+			artifact: &pack.DockerImage{Image: "registry.example.com/app", Tag: "1.0", Remote: true},
+			image:    "registry.example.com/app:1.0",
+		},
+		{
+			name:     "local pack image",
+			artifact: &pack.DockerImage{Image: "app", Tag: "1.0"},
+			err:      "image app:1.0 wasn't pushed to a registry",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var packageRequest []byte
+			server := fakeCloudController(t, false, func(route string, body []byte) {
+				if route == "POST /v3/packages" {
+					packageRequest = body
+				}
+			})
+			defer server.Close()
+			cftest.Target(t, server, fakeAccessToken, fakeRefreshToken)
+			t.Setenv(dockerConfigEnv, t.TempDir())
+
+			p := Platform{config: Config{
+				Organisation: "org",
+				Space:        "space",
+				Domain:       "example.com",
+			}}
+
+			f, err := argmapper.NewFunc(p.DeployFunc())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var ui terminal.UI = terminal.NonInteractiveUI(context.Background())
+			result := f.Call(
+				argmapper.ConverterFunc(mappers...),
+				argmapper.Typed(context.Background()),
+				argmapper.Typed(hclog.NewNullLogger()),
+				argmapper.Typed(&component.Source{App: "app"}),
+				argmapper.Typed(tc.artifact),
+				argmapper.Typed(&component.DeploymentConfig{}),
+				argmapper.Typed(ui),
+			)
+			if tc.err != "" {
+				assert.Error(t, result.Err())
+				if result.Err() != nil {
+					assert.Contains(t, result.Err().Error(), tc.err)
+				}
+				return
+			}
+			if err := result.Err(); err != nil {
+				t.Fatal(err)
+			}
+			assert.IsType(t, &Deployment{}, result.Out(0))
+			assert.Contains(t, string(packageRequest), fmt.Sprintf(`"image":"%s"`, tc.image))
+		})
+	}
+}
+
+// TestDeployMergesEnv checks that the env files override the Waypoint entrypoint configuration, in order,
+// that env overrides both, and that disable_entrypoint_env leaves out the entrypoint configuration
+func TestDeployMergesEnv(t *testing.T) {
+	for _, tc := range []struct {
+		name                 string
+		disableEntrypointEnv bool
+		expected             map[string]string
+	}{
+		{
+			name: "entrypoint env",
+			expected: map[string]string{
+				"WAYPOINT_DEPLOYMENT_ID":    "deployment-id",
+				"WAYPOINT_SERVER_ADDR":      "waypoint.example.org:9701",
+				"WAYPOINT_CEB_INVITE_TOKEN": "invite-token",
+				"DEPLOYMENT":                "deployment-id",
+				"FIRST_FILE":                "first",
+				"FILES":                     "second",
+				"OVERRIDDEN":                "env",
+			},
+		},
+		{
+			name:                 "disabled entrypoint env",
+			disableEntrypointEnv: true,
+			expected: map[string]string{
+				"WAYPOINT_SERVER_ADDR": "waypoint.example.org:9701",
+				"DEPLOYMENT":           "runner-deployment-id",
+				"FIRST_FILE":           "first",
+				"FILES":                "second",
+				"OVERRIDDEN":           "env",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var envRequest []byte
+			server := fakeCloudController(t, false, func(route string, body []byte) {
+				if route == "PATCH /v3/apps/app-guid/environment_variables" {
+					envRequest = body
+				}
+			})
+			defer server.Close()
+			cftest.Target(t, server, fakeAccessToken, fakeRefreshToken)
+			t.Setenv(dockerConfigEnv, t.TempDir())
+			// Without the entrypoint env, the env files are interpolated with the environment of the runner
+			t.Setenv("WAYPOINT_DEPLOYMENT_ID", "runner-deployment-id")
+
+			srcDir := t.TempDir()
+			files := map[string]string{
+				"first.env": "WAYPOINT_SERVER_ADDR=waypoint.example.org:9701\n" +
+					"DEPLOYMENT=${WAYPOINT_DEPLOYMENT_ID}\n" +
+					"FIRST_FILE=first\nFILES=first\nOVERRIDDEN=first\n",
+				"second.json": `{"FILES": "second", "OVERRIDDEN": "second"}`,
+			}
+			for name, content := range files {
+				if err := os.WriteFile(filepath.Join(srcDir, name), []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			p := Platform{config: Config{
+				Organisation:         "org",
+				Space:                "space",
+				Domain:               "example.com",
+				Env:                  map[string]string{"OVERRIDDEN": "env"},
+				EnvFromFile:          []string{"first.env", "second.json"},
+				DisableEntrypointEnv: tc.disableEntrypointEnv,
+			}}
+
+			_, err := p.Deploy(
+				context.Background(),
+				hclog.NewNullLogger(),
+				&component.Source{App: "app", Path: srcDir},
+				&docker.Image{Image: "registry.example.com/app", Tag: "1.0"},
+				&component.DeploymentConfig{
+					Id:                    "deployment-id",
+					ServerAddr:            "waypoint.example.com:9701",
+					EntrypointInviteToken: "invite-token",
+				},
+				terminal.NonInteractiveUI(context.Background()),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var env struct {
+				Var map[string]string `json:"var"`
+			}
+			assert.NoError(t, json.Unmarshal(envRequest, &env))
+			assert.Equal(t, tc.expected, env.Var)
+		})
+	}
+}
@@ -90,7 +90,7 @@ func (p *Platform) destroy(
 	}
 	for _, route := range routes {
 		// only delete if it's the automatically created deployment route
-		if route.Host == deploymentName {
+		if route.GUID == deployment.RouteGUID || route.Host == deploymentName {
 			_, err = client.DeleteRoute(route.GUID)
 			if err != nil {
 				step.Update(fmt.Sprintf("%v [failed to delete route]", stepDescription))
@@ -99,6 +99,30 @@ func (p *Platform) destroy(
 	}
 	step.Done()
 
+	if len(p.config.NetworkPolicies) > 0 {
+		step = sg.Add(fmt.Sprintf("Deleting network policies for %v", deploymentName))
+		policies, err := client.GetNetworkPolicies(app.GUID)
+		if err != nil {
+			step.Abort()
+			return fmt.Errorf("failed to get network policies: %v", err)
+		}
+		var appPolicies []cloudfoundry.NetworkPolicy
+		for _, policy := range policies {
+			// only delete the policies allowing traffic to this app
+			if policy.Destination.ID == app.GUID {
+				appPolicies = append(appPolicies, policy)
+			}
+		}
+		if len(appPolicies) > 0 {
+			err = client.DeleteNetworkPolicies(appPolicies)
+			if err != nil {
+				step.Abort()
+				return fmt.Errorf("failed to delete network policies: %v", err)
+			}
+		}
+		step.Done()
+	}
+
 	step = sg.Add(fmt.Sprintf("Deleting app %v", app.Name))
 	_, err = client.DeleteApplication(app.GUID)
 	if err != nil {
@@ -0,0 +1,85 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+// Environment variables of the docker credentials
+const (
+	dockerPasswordEnv = "CF_DOCKER_PASSWORD"
+	dockerConfigEnv   = "DOCKER_CONFIG"
+)
+
+// dockerCredentials resolves the credentials CF pulls the image with, in order from:
+//   - docker.username with docker.password or CF_DOCKER_PASSWORD
+//   - docker_encoded_auth
+//   - the entry of the image registry in the docker config.json
+//
+// nil is returned for public images. source describes where the credentials come from. The auth of the
+// Waypoint registry plugin isn't a source: Waypoint only passes the pushed image to platforms, not the
+// registry access info.
+func (p *Platform) dockerCredentials(image string) (
+	credentials *UserPasswordCredentials, source string, err error,
+) {
+	credentials, source, err = p.resolveDockerCredentials(image)
+	if credentials != nil {
+		p.redactor.AddExplicit(credentials.Password)
+	}
+	return credentials, source, err
+}
+
+func (p *Platform) resolveDockerCredentials(image string) (
+	*UserPasswordCredentials, string, error,
+) {
+	if p.config.Docker != nil && p.config.Docker.Username != "" {
+		password := p.config.Docker.Password
+		if password == "" {
+			password = os.Getenv(dockerPasswordEnv)
+		}
+		if password == "" {
+			return nil, "", fmt.Errorf("invalid docker credentials: %s", errDockerPasswordEmpty)
+		}
+		return &UserPasswordCredentials{Username: p.config.Docker.Username, Password: password}, "docker.username", nil
+	}
+
+	if p.config.DockerEncodedAuth != "" {
+		p.redactor.AddExplicit(p.config.DockerEncodedAuth)
+		username, password, err := utils.DecodeDockerAuth(p.config.DockerEncodedAuth)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid docker_encoded_auth: %v", err)
+		}
+		return &UserPasswordCredentials{Username: username, Password: password}, "docker_encoded_auth", nil
+	}
+
+	if path, explicit := p.dockerConfigPath(); path != "" {
+		username, password, ok, err := utils.ReadDockerConfigCredentials(path, utils.ImageRegistry(image))
+		if err != nil && (explicit || !os.IsNotExist(err)) {
+			return nil, "", fmt.Errorf("unable to read docker config %s: %v", path, err)
+		}
+		if ok {
+			return &UserPasswordCredentials{Username: username, Password: password}, path, nil
+		}
+	}
+
+	return nil, "", nil
+}
+
+// dockerConfigPath returns the path of the docker config.json: docker.config_file, $DOCKER_CONFIG/config.json
+// or ~/.docker/config.json. explicit is true when the file was configured, and has to exist.
+func (p *Platform) dockerConfigPath() (path string, explicit bool) {
+	if p.config.Docker != nil && p.config.Docker.ConfigFile != "" {
+		return p.config.Docker.ConfigFile, true
+	}
+	if dir := os.Getenv(dockerConfigEnv); dir != "" {
+		return filepath.Join(dir, "config.json"), false
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(home, ".docker", "config.json"), false
+}
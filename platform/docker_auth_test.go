@@ -0,0 +1,110 @@
+package platform
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerCredentials(t *testing.T) {
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	dockerConfigDir := t.TempDir()
+	dockerConfig := `{"auths": {"registry.example.com": {"auth": "` + encode("config-user:config-password") + `"}}}`
+	err := os.WriteFile(filepath.Join(dockerConfigDir, "config.json"), []byte(dockerConfig), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		config   Config
+		image    string
+		env      string
+		username string
+		password string
+		source   string
+		err      string
+	}{
+		{
+			name:     "username and password",
+			config:   Config{Docker: &DockerConfig{Username: "user", Password: "password"}, DockerEncodedAuth: encode("a:b")},
+			image:    "registry.example.com/app",
+			username: "user",
+			password: "password",
+			source:   "docker.username",
+		},
+		{
+			name:     "username and CF_DOCKER_PASSWORD",
+			config:   Config{Docker: &DockerConfig{Username: "user"}},
+			env:      "env-password",
+			username: "user",
+			password: "env-password",
+			source:   "docker.username",
+		},
+		{
+			name:   "username without password",
+			config: Config{Docker: &DockerConfig{Username: "user"}},
+			err:    "invalid docker credentials: " + errDockerPasswordEmpty,
+		},
+		{
+			name:     "encoded auth",
+			config:   Config{DockerEncodedAuth: encode("encoded-user:encoded-password") + "\n"},
+			image:    "registry.example.com/app",
+			username: "encoded-user",
+			password: "encoded-password",
+			source:   "docker_encoded_auth",
+		},
+		{
+			name:   "invalid encoded auth",
+			config: Config{DockerEncodedAuth: encode("user")},
+			err:    "invalid docker_encoded_auth: credentials have to be encoded as username:password",
+		},
+		{
+			name:     "docker config",
+			image:    "registry.example.com/app:1.0",
+			username: "config-user",
+			password: "config-password",
+			source:   filepath.Join(dockerConfigDir, "config.json"),
+		},
+		{
+			name:  "registry without credentials",
+			image: "other.example.com/app:1.0",
+		},
+		{
+			name:  "public image",
+			image: "nginx",
+		},
+		{
+			name:   "missing config file",
+			config: Config{Docker: &DockerConfig{ConfigFile: filepath.Join(dockerConfigDir, "missing.json")}},
+			image:  "nginx",
+			err: "unable to read docker config " + filepath.Join(dockerConfigDir, "missing.json") +
+				": open " + filepath.Join(dockerConfigDir, "missing.json") + ": no such file or directory",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(dockerPasswordEnv, tc.env)
+			t.Setenv(dockerConfigEnv, dockerConfigDir)
+
+			p := Platform{config: tc.config}
+			credentials, source, err := p.dockerCredentials(tc.image)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.source, source)
+			if tc.username == "" {
+				assert.Nil(t, credentials)
+				return
+			}
+			assert.Equal(t, &UserPasswordCredentials{Username: tc.username, Password: tc.password}, credentials)
+		})
+	}
+}
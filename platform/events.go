@@ -0,0 +1,84 @@
+package platform
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+const (
+	// appEventsPageSize is the number of audit events fetched, before filtering the relevant ones
+	appEventsPageSize = 50
+	// appEventsShown is the maximum number of relevant audit events shown to the user
+	appEventsShown = 10
+)
+
+// recentAppEvents returns the latest crash, droplet and update events of an app, the most recent first
+func (p *Platform) recentAppEvents(client *cloudfoundry.Client, appGuid string) ([]ccv3.Event, error) {
+	events, err := client.GetAppEvents(appGuid, appEventsPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get events of app %v: %v", appGuid, err)
+	}
+
+	var result []ccv3.Event
+	for _, event := range events {
+		if utils.IsRelevantAppEvent(event.Type) {
+			result = append(result, event)
+		}
+		if len(result) == appEventsShown {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (p *Platform) outputAppEvents(ui terminal.UI, client *cloudfoundry.Client, appGuid string) {
+	events, err := p.recentAppEvents(client, appGuid)
+	if err != nil {
+		p.log.Warn("unable to show recent app events", "error", err)
+		return
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	ui.Output("Recent events of app %v:", appGuid, terminal.WithHeaderStyle())
+	for _, event := range events {
+		ui.Output("%s  %s: %s",
+			event.CreatedAt.Format(time.RFC3339),
+			event.Type,
+			utils.DescribeAppEvent(event.Type, event.Data),
+			terminal.WithInfoStyle(),
+		)
+	}
+}
+
+// outputAppEventsOnFail shows the recent events of the app when the deployment fails,
+// before the app gets deleted by cleanupResourcesOnFail
+func (p *Platform) outputAppEventsOnFail(state *DeploymentState, ui terminal.UI) {
+	if !state.shouldCleanup || state.deployment.AppGUID == "" {
+		return
+	}
+	p.outputAppEvents(ui, state.client, state.deployment.AppGUID)
+}
+
+// crashReason returns the exit description of the latest crash of an instance, if any
+func (p *Platform) crashReason(state *DeploymentState, instance ccv3.ProcessInstance) string {
+	events, err := p.recentAppEvents(state.client, state.deployment.AppGUID)
+	if err != nil {
+		p.log.Warn("unable to get the crash reason", "error", err)
+		return ""
+	}
+
+	for _, event := range events {
+		index, ok := event.Data["index"].(float64)
+		if event.Type == utils.AppProcessCrashEvent && ok && int64(index) == instance.Index {
+			return utils.DescribeAppEvent(event.Type, event.Data)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package platform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/waypoint/builtin/aws/ecr"
+	"github.com/hashicorp/waypoint/builtin/docker"
+	"github.com/hashicorp/waypoint/builtin/pack"
+)
+
+// Mappers converts the artifacts of other registry and builder plugins to the *docker.Image deployed by
+// Deploy. They are registered with the plugin, so that Waypoint can use them when the registry doesn't
+// output a *docker.Image.
+var Mappers = []interface{}{
+	ECRImageMapper,
+	PackImageMapper,
+}
+
+// ECRImageMapper maps the image pushed by the aws-ecr registry plugin. CF pulls it with the configured
+// docker credentials, ECR doesn't provide any.
+func ECRImageMapper(img *ecr.Image) *docker.Image {
+	return &docker.Image{
+		Image:    img.Image,
+		Tag:      img.Tag,
+		Location: &docker.Image_Registry{},
+	}
+}
+
+// PackImageMapper maps the image built by the pack builder, when it was published to a registry.
+// Images only built into the local docker daemon can't be pulled by CF.
+func PackImageMapper(img *pack.DockerImage) (*docker.Image, error) {
+	if !img.Remote {
+		return nil, fmt.Errorf("image %s wasn't pushed to a registry, configure a registry to push it",
+			img.Name())
+	}
+	return &docker.Image{
+		Image:    img.Image,
+		Tag:      img.Tag,
+		Location: &docker.Image_Registry{},
+	}, nil
+}
@@ -0,0 +1,124 @@
+package platform
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/cli/resources"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+const (
+	DefaultNetworkPolicyPorts    = "8080"
+	DefaultNetworkPolicyProtocol = "tcp"
+)
+
+func validateNetworkPolicy(policy *NetworkPolicyConfig) error {
+	if (policy.SourceApp == "") == (policy.SourceLabel == "") {
+		return fmt.Errorf("exactly one of source_app and source_label must be set")
+	}
+
+	switch policy.Protocol {
+	case "", "tcp", "udp":
+	default:
+		return fmt.Errorf("protocol has to be tcp or udp, got %q", policy.Protocol)
+	}
+
+	if policy.Ports != "" {
+		if _, _, err := utils.ParsePortRange(policy.Ports); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createNetworkPolicies allows the configured source apps to reach the deployed app
+func (p *Platform) createNetworkPolicies(state *DeploymentState) error {
+	if len(p.config.NetworkPolicies) == 0 {
+		return nil
+	}
+
+	step := (*state.sg).Add("Creating network policies")
+	var policies []cloudfoundry.NetworkPolicy
+	for _, policyConfig := range p.config.NetworkPolicies {
+		sources, err := p.networkPolicySources(state, policyConfig)
+		if err != nil {
+			step.Abort()
+			return err
+		}
+
+		protocol := policyConfig.Protocol
+		if protocol == "" {
+			protocol = DefaultNetworkPolicyProtocol
+		}
+
+		ports := policyConfig.Ports
+		if ports == "" {
+			ports = DefaultNetworkPolicyPorts
+		}
+		start, end, err := utils.ParsePortRange(ports)
+		if err != nil {
+			step.Abort()
+			return fmt.Errorf("invalid network policy ports: %v", err)
+		}
+
+		for _, source := range sources {
+			if source.GUID == state.deployment.AppGUID {
+				continue
+			}
+			policies = append(policies, cloudfoundry.NetworkPolicy{
+				Source: cloudfoundry.NetworkPolicySource{ID: source.GUID},
+				Destination: cloudfoundry.NetworkPolicyDestination{
+					ID:       state.deployment.AppGUID,
+					Protocol: protocol,
+					Ports:    cloudfoundry.NetworkPolicyPorts{Start: start, End: end},
+				},
+			})
+		}
+	}
+
+	if len(policies) > 0 {
+		err := state.client.CreateNetworkPolicies(policies)
+		if err != nil {
+			step.Abort()
+			return fmt.Errorf("failed to create network policies: %v", err)
+		}
+	}
+	step.Update(fmt.Sprintf("Created %d network policies", len(policies)))
+	step.Done()
+	return nil
+}
+
+// networkPolicySources returns the apps of the deployment space matching the source of the policy
+func (p *Platform) networkPolicySources(
+	state *DeploymentState,
+	policyConfig *NetworkPolicyConfig,
+) ([]resources.Application, error) {
+	if policyConfig.SourceApp != "" {
+		apps, err := state.client.GetApplications(
+			state.deployment.OrganisationGUID,
+			state.deployment.SpaceGUID,
+			policyConfig.SourceApp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get source app %v: %v", policyConfig.SourceApp, err)
+		}
+		if len(apps) == 0 {
+			return nil, fmt.Errorf("source app %v not found", policyConfig.SourceApp)
+		}
+		return apps, nil
+	}
+
+	apps, err := state.client.GetApplicationsByLabels(
+		state.deployment.OrganisationGUID,
+		state.deployment.SpaceGUID,
+		[]string{policyConfig.SourceLabel},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get source apps with label %v: %v", policyConfig.SourceLabel, err)
+	}
+	if len(apps) == 0 {
+		p.log.Warn("no source apps found for network policy", "label", policyConfig.SourceLabel)
+	}
+	return apps, nil
+}
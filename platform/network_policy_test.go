@@ -0,0 +1,242 @@
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry/cftest"
+)
+
+// networkPolicyController serves the Cloud Controller and network policy API requests of the network
+// policies of app-guid, and records the policies created and deleted. The app frontend and the apps
+// labeled appName=frontend are the sources, app-guid is labeled too.
+type networkPolicyController struct {
+	t       *testing.T
+	created []cloudfoundry.NetworkPolicy
+	deleted []cloudfoundry.NetworkPolicy
+}
+
+func newNetworkPolicyController(t *testing.T) *networkPolicyController {
+	cc := &networkPolicyController{t: t}
+	server := httptest.NewServer(http.HandlerFunc(cc.serve))
+	t.Cleanup(server.Close)
+	cftest.Target(t, server, fakeAccessToken, fakeRefreshToken)
+	return cc
+}
+
+func (cc *networkPolicyController) serve(w http.ResponseWriter, r *http.Request) {
+	app := func(guid string, name string) map[string]interface{} {
+		return map[string]interface{}{"guid": guid, "name": name, "state": "STARTED"}
+	}
+	policy := func(source string, destination string) cloudfoundry.NetworkPolicy {
+		return cloudfoundry.NetworkPolicy{
+			Source: cloudfoundry.NetworkPolicySource{ID: source},
+			Destination: cloudfoundry.NetworkPolicyDestination{
+				ID:       destination,
+				Protocol: "tcp",
+				Ports:    cloudfoundry.NetworkPolicyPorts{Start: 8080, End: 8080},
+			},
+		}
+	}
+
+	var response interface{}
+	status := http.StatusOK
+	switch route := r.Method + " " + r.URL.Path; route {
+	case "POST /oauth/token":
+		response = map[string]interface{}{
+			"access_token":  fakeAccessToken,
+			"refresh_token": fakeRefreshToken,
+			"token_type":    "bearer",
+		}
+	case "GET /":
+		response = map[string]interface{}{"links": map[string]interface{}{
+			"network_policy_v1": map[string]interface{}{"href": "http://" + r.Host + "/networking/v1/external"},
+		}}
+	case "GET /v3/apps":
+		query := r.URL.Query()
+		switch {
+		case query.Get("names") == "frontend":
+			response = cftest.List(app("frontend-guid", "frontend"))
+		case query.Get("names") == "app-deployment":
+			response = cftest.List(app("app-guid", "app-deployment"))
+		case query.Get("label_selector") == "appName=frontend":
+			response = cftest.List(
+				app("frontend-1-guid", "frontend-1"),
+				app("frontend-2-guid", "frontend-2"),
+				app("app-guid", "app-deployment"),
+			)
+		default:
+			response = cftest.List()
+		}
+	case "GET /v3/apps/app-guid/routes":
+		response = cftest.List()
+	case "DELETE /v3/apps/app-guid":
+		status = http.StatusAccepted
+	case "GET /networking/v1/external/policies":
+		// The policies in which app-guid is either the source or the destination
+		response = map[string]interface{}{"policies": []cloudfoundry.NetworkPolicy{
+			policy("frontend-guid", "app-guid"),
+			policy("app-guid", "backend-guid"),
+			policy("frontend-1-guid", "app-guid"),
+		}}
+	case "POST /networking/v1/external/policies":
+		cc.created = append(cc.created, cc.policies(r)...)
+	case "POST /networking/v1/external/policies/delete":
+		cc.deleted = append(cc.deleted, cc.policies(r)...)
+	default:
+		cc.t.Errorf("unexpected request %s", route)
+		status = http.StatusNotFound
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if response != nil {
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+func (cc *networkPolicyController) policies(r *http.Request) []cloudfoundry.NetworkPolicy {
+	var body struct {
+		Policies []cloudfoundry.NetworkPolicy `json:"policies"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		cc.t.Errorf("invalid network policies: %v", err)
+	}
+	return body.Policies
+}
+
+func TestValidateNetworkPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy NetworkPolicyConfig
+		err    string
+	}{
+		{name: "default ports", policy: NetworkPolicyConfig{SourceApp: "frontend"}},
+		{name: "single port", policy: NetworkPolicyConfig{SourceApp: "frontend", Ports: "9090"}},
+		{name: "port range", policy: NetworkPolicyConfig{SourceLabel: "appName=frontend", Ports: "8080-8090"}},
+		{name: "udp", policy: NetworkPolicyConfig{SourceApp: "frontend", Protocol: "udp"}},
+		{
+			name:   "reversed port range",
+			policy: NetworkPolicyConfig{SourceApp: "frontend", Ports: "8090-8080"},
+			err:    "end port is lower than start port",
+		},
+		{
+			name:   "port out of range",
+			policy: NetworkPolicyConfig{SourceApp: "frontend", Ports: "8080-70000"},
+			err:    "70000",
+		},
+		{
+			name:   "invalid port",
+			policy: NetworkPolicyConfig{SourceApp: "frontend", Ports: "http"},
+			err:    `invalid port "http"`,
+		},
+		{
+			name:   "invalid protocol",
+			policy: NetworkPolicyConfig{SourceApp: "frontend", Protocol: "icmp"},
+			err:    "protocol has to be tcp or udp",
+		},
+		{name: "no source", policy: NetworkPolicyConfig{}, err: "exactly one of source_app and source_label"},
+		{
+			name:   "both sources",
+			policy: NetworkPolicyConfig{SourceApp: "frontend", SourceLabel: "appName=frontend"},
+			err:    "exactly one of source_app and source_label",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateNetworkPolicy(&tc.policy)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+// createNetworkPolicies creates the policies of the configuration for the deployed app-guid
+func createNetworkPolicies(t *testing.T, policies ...*NetworkPolicyConfig) error {
+	client, err := cloudfoundry.New(hclog.NewNullLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg := terminal.NonInteractiveUI(context.Background()).StepGroup()
+	defer sg.Wait()
+
+	p := Platform{config: Config{NetworkPolicies: policies}, log: hclog.NewNullLogger()}
+	return p.createNetworkPolicies(&DeploymentState{
+		sg:         &sg,
+		client:     client,
+		deployment: &Deployment{AppGUID: "app-guid", OrganisationGUID: "org-guid", SpaceGUID: "space-guid"},
+	})
+}
+
+func TestCreateNetworkPolicies(t *testing.T) {
+	cc := newNetworkPolicyController(t)
+
+	err := createNetworkPolicies(t,
+		&NetworkPolicyConfig{SourceApp: "frontend", Ports: "8080-8090"},
+		&NetworkPolicyConfig{SourceLabel: "appName=frontend", Protocol: "udp"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	destination := func(protocol string, start int, end int) cloudfoundry.NetworkPolicyDestination {
+		return cloudfoundry.NetworkPolicyDestination{
+			ID:       "app-guid",
+			Protocol: protocol,
+			Ports:    cloudfoundry.NetworkPolicyPorts{Start: start, End: end},
+		}
+	}
+	// The deployed app matching the label isn't allowed to reach itself
+	assert.Equal(t, []cloudfoundry.NetworkPolicy{
+		{Source: cloudfoundry.NetworkPolicySource{ID: "frontend-guid"}, Destination: destination("tcp", 8080, 8090)},
+		{Source: cloudfoundry.NetworkPolicySource{ID: "frontend-1-guid"}, Destination: destination("udp", 8080, 8080)},
+		{Source: cloudfoundry.NetworkPolicySource{ID: "frontend-2-guid"}, Destination: destination("udp", 8080, 8080)},
+	}, cc.created)
+}
+
+func TestCreateNetworkPoliciesMissingSourceApp(t *testing.T) {
+	cc := newNetworkPolicyController(t)
+
+	err := createNetworkPolicies(t,
+		&NetworkPolicyConfig{SourceApp: "frontend"},
+		&NetworkPolicyConfig{SourceApp: "missing"},
+	)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "source app missing not found")
+	}
+	assert.Empty(t, cc.created)
+}
+
+func TestDestroyDeletesNetworkPolicies(t *testing.T) {
+	cc := newNetworkPolicyController(t)
+
+	p := Platform{config: Config{NetworkPolicies: []*NetworkPolicyConfig{{SourceApp: "frontend"}}}}
+	err := p.destroy(
+		context.Background(),
+		hclog.NewNullLogger(),
+		terminal.NonInteractiveUI(context.Background()),
+		&Deployment{Id: "deployment", OrganisationGUID: "org-guid", SpaceGUID: "space-guid"},
+		&component.Source{App: "app"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The policies of other apps reached by the app are kept
+	var sources []string
+	for _, policy := range cc.deleted {
+		assert.Equal(t, "app-guid", policy.Destination.ID)
+		sources = append(sources, policy.Source.ID)
+	}
+	assert.Equal(t, []string{"frontend-guid", "frontend-1-guid"}, sources)
+}
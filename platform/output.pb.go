@@ -33,6 +33,9 @@ type Deployment struct {
 	SpaceGUID        string `protobuf:"bytes,4,opt,name=SpaceGUID,proto3" json:"SpaceGUID,omitempty"`
 	AppGUID          string `protobuf:"bytes,5,opt,name=AppGUID,proto3" json:"AppGUID,omitempty"`
 	Name             string `protobuf:"bytes,6,opt,name=Name,proto3" json:"Name,omitempty"`
+	RouteGUID        string `protobuf:"bytes,7,opt,name=RouteGUID,proto3" json:"RouteGUID,omitempty"`
+	AppPort          int32  `protobuf:"varint,8,opt,name=AppPort,proto3" json:"AppPort,omitempty"`
+	ImageDigest      string `protobuf:"bytes,9,opt,name=ImageDigest,proto3" json:"ImageDigest,omitempty"`
 }
 
 func (x *Deployment) Reset() {
@@ -109,12 +112,33 @@ func (x *Deployment) GetName() string {
 	return ""
 }
 
+func (x *Deployment) GetRouteGUID() string {
+	if x != nil {
+		return x.RouteGUID
+	}
+	return ""
+}
+
+func (x *Deployment) GetAppPort() int32 {
+	if x != nil {
+		return x.AppPort
+	}
+	return 0
+}
+
+func (x *Deployment) GetImageDigest() string {
+	if x != nil {
+		return x.ImageDigest
+	}
+	return ""
+}
+
 var File_platform_output_proto protoreflect.FileDescriptor
 
 var file_platform_output_proto_rawDesc = []byte{
 	0x0a, 0x15, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x2f, 0x6f, 0x75, 0x74, 0x70, 0x75,
 	0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72,
-	0x6d, 0x22, 0xa6, 0x01, 0x0a, 0x0a, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74,
+	0x6d, 0x22, 0x80, 0x02, 0x0a, 0x0a, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74,
 	0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75,
 	0x72, 0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02,
 	0x69, 0x64, 0x12, 0x2a, 0x0a, 0x10, 0x4f, 0x72, 0x67, 0x61, 0x6e, 0x69, 0x73, 0x61, 0x74, 0x69,
@@ -124,11 +148,17 @@ var file_platform_output_proto_rawDesc = []byte{
 	0x09, 0x52, 0x09, 0x53, 0x70, 0x61, 0x63, 0x65, 0x47, 0x55, 0x49, 0x44, 0x12, 0x18, 0x0a, 0x07,
 	0x41, 0x70, 0x70, 0x47, 0x55, 0x49, 0x44, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x41,
 	0x70, 0x70, 0x47, 0x55, 0x49, 0x44, 0x12, 0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x06,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x42, 0x3b, 0x5a, 0x39, 0x67, 0x69,
-	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x77, 0x69, 0x73, 0x73, 0x63, 0x6f,
-	0x6d, 0x2f, 0x77, 0x61, 0x79, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x2d, 0x70, 0x6c, 0x75, 0x67, 0x69,
-	0x6e, 0x2d, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x72, 0x79, 0x2f, 0x70,
-	0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x52, 0x6f,
+	0x75, 0x74, 0x65, 0x47, 0x55, 0x49, 0x44, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x52,
+	0x6f, 0x75, 0x74, 0x65, 0x47, 0x55, 0x49, 0x44, 0x12, 0x18, 0x0a, 0x07, 0x41, 0x70, 0x70, 0x50,
+	0x6f, 0x72, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x41, 0x70, 0x70, 0x50, 0x6f,
+	0x72, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x49, 0x6d, 0x61, 0x67, 0x65, 0x44, 0x69, 0x67, 0x65, 0x73,
+	0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x49, 0x6d, 0x61, 0x67, 0x65, 0x44, 0x69,
+	0x67, 0x65, 0x73, 0x74, 0x42, 0x3b, 0x5a, 0x39, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x73, 0x77, 0x69, 0x73, 0x73, 0x63, 0x6f, 0x6d, 0x2f, 0x77, 0x61, 0x79, 0x70,
+	0x6f, 0x69, 0x6e, 0x74, 0x2d, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2d, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x72, 0x79, 0x2f, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72,
+	0x6d, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
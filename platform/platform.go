@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"code.cloudfoundry.org/cli/types"
@@ -12,6 +14,7 @@ import (
 	proto "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
 	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry"
 	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"k8s.io/apimachinery/pkg/api/resource"
 
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
@@ -45,27 +48,112 @@ type HealthCheckConfig struct {
 	Timeout           int64  `hcl:"timeout,optional"`
 }
 
+// ReadinessCheckConfig describes the readiness health check of the processes. Instances failing it
+// are kept running, but don't receive traffic from the routes.
+type ReadinessCheckConfig struct {
+	Type              string `hcl:"type"`
+	Endpoint          string `hcl:"endpoint,optional"`
+	InvocationTimeout int64  `hcl:"invocation_timeout,optional"`
+	Interval          int64  `hcl:"interval,optional"`
+}
+
+// CredHubConfig configures the CredHub API used to resolve env values like "credhub:/team/app/db-password"
+type CredHubConfig struct {
+	URL string `hcl:"url,optional"`
+	// ClientID and ClientSecret are UAA client credentials, the token of the Cloud Foundry user is used if not set
+	ClientID     string `hcl:"client_id,optional"`
+	ClientSecret string `hcl:"client_secret,optional"`
+}
+
+// HealthPolicyConfig selects how the health of the instances is aggregated in status reports
+type HealthPolicyConfig struct {
+	// Type is worst-of (default) or quorum
+	Type          string `hcl:"type,optional"`
+	QuorumPercent int    `hcl:"quorum_percent,optional"`
+	// StartingGracePeriod is a duration like "2m" during which starting instances are ignored
+	StartingGracePeriod string `hcl:"starting_grace_period,optional"`
+}
+
+// Policy returns the configured health policy, or nil if none is configured
+func (c *HealthPolicyConfig) Policy() (utils.HealthPolicy, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	var gracePeriod time.Duration
+	if c.StartingGracePeriod != "" {
+		var err error
+		gracePeriod, err = time.ParseDuration(c.StartingGracePeriod)
+		if err != nil {
+			return nil, fmt.Errorf("invalid starting_grace_period: %v", err)
+		}
+	}
+	return utils.NewHealthPolicy(c.Type, c.QuorumPercent, gracePeriod)
+}
+
+type NetworkPolicyConfig struct {
+	// Either the name of an app in the same space, or a label selector (e.g. appName=frontend)
+	SourceApp   string `hcl:"source_app,optional"`
+	SourceLabel string `hcl:"source_label,optional"`
+	Protocol    string `hcl:"protocol,optional"`
+	Ports       string `hcl:"ports,optional"`
+}
+
+// ProcessConfig overrides the start command of a process. Processes other than web are
+// created if the image doesn't define them.
+type ProcessConfig struct {
+	Type    string `hcl:"type"`
+	Command string `hcl:"command"`
+	// Instances of the process, 1 if not set, 0 to keep it stopped. The instances of web are set by quota.instances
+	Instances *int `hcl:"instances,optional"`
+}
+
 type DockerConfig struct {
-	Username string `hcl:"username"`
+	Username string `hcl:"username,optional"`
+	// Password of the user, CF_DOCKER_PASSWORD is used if empty
+	Password string `hcl:"password,optional"`
+	// ConfigFile is a docker config.json with the credentials of the image registry
+	ConfigFile string `hcl:"config_file,optional"`
+	// Insecure registries are served over HTTP, e.g. a local registry
+	Insecure bool `hcl:"insecure,optional"`
 }
 
 type Config struct {
-	Organisation             string             `hcl:"organisation"`
-	Space                    string             `hcl:"space"`
-	Docker                   *DockerConfig      `hcl:"docker,block"`
-	Domain                   string             `hcl:"domain"`
-	Quota                    *QuotaConfig       `hcl:"quota,block"`
-	HealthCheck              *HealthCheckConfig `hcl:"health_check,block"`
-	Env                      map[string]string  `hcl:"env,optional"`
-	EnvFromFile              string             `hcl:"env_from_file,optional"`
-	ServiceBindings          []string           `hcl:"service_bindings,optional"`
-	DeploymentTimeoutSeconds string             `hcl:"deployment_timeout_seconds,optional"`
+	Organisation             string                 `hcl:"organisation"`
+	Space                    string                 `hcl:"space"`
+	Docker                   *DockerConfig          `hcl:"docker,block"`
+	DockerEncodedAuth        string                 `hcl:"docker_encoded_auth,optional"`
+	PinImageDigest           bool                   `hcl:"pin_image_digest,optional"`
+	Command                  string                 `hcl:"command,optional"`
+	Processes                []*ProcessConfig       `hcl:"process,block"`
+	Domain                   string                 `hcl:"domain"`
+	RoutePath                string                 `hcl:"route_path,optional"`
+	RoutePort                int                    `hcl:"route_port,optional"`
+	AppPort                  int                    `hcl:"app_port,optional"`
+	Ports                    []int                  `hcl:"ports,optional"`
+	RouteLoadBalancing       string                 `hcl:"route_loadbalancing,optional"`
+	RouteProtocol            string                 `hcl:"route_protocol,optional"`
+	NetworkPolicies          []*NetworkPolicyConfig `hcl:"network_policy,block"`
+	Quota                    *QuotaConfig           `hcl:"quota,block"`
+	HealthCheck              *HealthCheckConfig     `hcl:"health_check,block"`
+	ReadinessCheck           *ReadinessCheckConfig  `hcl:"readiness_check,block"`
+	Env                      map[string]string      `hcl:"env,optional"`
+	EnvFromFile              []string               `hcl:"env_from_file,optional"`
+	SensitiveEnv             []string               `hcl:"sensitive_env,optional"`
+	DisableEntrypointEnv     bool                   `hcl:"disable_entrypoint_env,optional"`
+	CredHub                  *CredHubConfig         `hcl:"credhub,block"`
+	ServiceBindings          []string               `hcl:"service_bindings,optional"`
+	DeploymentTimeoutSeconds string                 `hcl:"deployment_timeout_seconds,optional"`
+	UsageThresholdPercent    int                    `hcl:"usage_threshold_percent,optional"`
+	HealthPolicy             *HealthPolicyConfig    `hcl:"health_policy,block"`
 	deploymentTimeout        time.Duration
 }
 
 type Platform struct {
 	config Config
 	log    hclog.Logger
+	// redactor masks the secrets of the deployment in logs, UI output and errors
+	redactor *utils.Redactor
 }
 
 func (p *Platform) StatusFunc() interface{} {
@@ -80,6 +168,7 @@ func (p *Platform) Status(
 ) (*proto.StatusReport, error) {
 	var result proto.StatusReport
 	result.External = true
+	p.log = log
 
 	sg := ui.StepGroup()
 	defer sg.Wait()
@@ -100,13 +189,75 @@ func (p *Platform) Status(
 		return nil, err
 	}
 
+	policy, err := p.config.HealthPolicy.Policy()
+	if err != nil {
+		step.Abort()
+		return nil, fmt.Errorf("invalid health_policy: %v", err)
+	}
+
 	// Get processes by app
-	theResult, err := state.client.GetHealthByGUID(deployment.AppGUID)
+	theResult, err := state.client.GetHealthByGUID(deployment.AppGUID, policy)
+	if err != nil {
+		step.Abort()
+		return nil, fmt.Errorf("unable to get health of app %v: %v", deployment.AppGUID, err)
+	}
+	theResult.External = result.External
+	theResult.GeneratedTime = timestamppb.Now()
+
+	// Metrics are best effort, log-cache may not be available on every foundation
+	metrics, err := state.client.GetContainerMetrics(ctx, deployment.AppGUID)
+	if err != nil {
+		log.Warn("unable to get container metrics", "error", err)
+	} else {
+		p.flagQuotaUsage(theResult, metrics)
+	}
 
 	step.Done()
+	p.outputAppEvents(ui, state.client, deployment.AppGUID)
 	return theResult, nil
 }
 
+// flagQuotaUsage marks instances using more than the configured share of their quota as PARTIAL
+func (p *Platform) flagQuotaUsage(report *proto.StatusReport, metrics []cloudfoundry.ContainerMetrics) {
+	threshold := p.config.UsageThresholdPercent
+	if threshold == 0 {
+		threshold = utils.DefaultUsageThresholdPercent
+	}
+
+	byName := map[string]cloudfoundry.ContainerMetrics{}
+	for _, m := range metrics {
+		byName[fmt.Sprintf("%s/%d", m.ProcessType, m.InstanceIndex)] = m
+	}
+
+	var flagged []string
+	for _, resource := range report.Resources {
+		m, ok := byName[resource.Name]
+		if resource.Type != "instance" || !ok {
+			continue
+		}
+
+		message := utils.QuotaUsageMessage(m.Memory, m.MemoryQuota, m.Disk, m.DiskQuota, threshold)
+		if message == "" {
+			continue
+		}
+
+		if resource.Health == proto.StatusReport_READY {
+			resource.Health = proto.StatusReport_PARTIAL
+		}
+		resource.HealthMessage = fmt.Sprintf("%s, %s", resource.HealthMessage, message)
+		flagged = append(flagged, resource.Name)
+	}
+
+	if len(flagged) == 0 {
+		return
+	}
+	if report.Health == proto.StatusReport_READY {
+		report.Health = proto.StatusReport_PARTIAL
+	}
+	report.HealthMessage = fmt.Sprintf("%s, instances close to their quota: %s",
+		report.HealthMessage, strings.Join(flagged, ", "))
+}
+
 // Config implements Configurable
 func (p *Platform) Config() (interface{}, error) {
 	return &p.config, nil
@@ -114,12 +265,62 @@ func (p *Platform) Config() (interface{}, error) {
 
 // ConfigSet implements ConfigurableNotify
 func (p *Platform) ConfigSet(config interface{}) error {
-	_, ok := config.(*Config)
+	c, ok := config.(*Config)
 	if !ok {
 		// The Waypoint SDK should ensure this never gets hit
 		return fmt.Errorf("expected *Config as parameter")
 	}
 
+	if err := utils.ValidateRoutePath(c.RoutePath); err != nil {
+		return fmt.Errorf("invalid route_path: %v", err)
+	}
+
+	for _, port := range c.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid ports: %d is not in range 1-65535", port)
+		}
+	}
+
+	if err := utils.ValidateRouteOptions(c.RouteLoadBalancing, c.RouteProtocol); err != nil {
+		return fmt.Errorf("invalid route options: %v", err)
+	}
+
+	for _, policy := range c.NetworkPolicies {
+		if err := validateNetworkPolicy(policy); err != nil {
+			return fmt.Errorf("invalid network_policy: %v", err)
+		}
+	}
+
+	if err := validateProcesses(c); err != nil {
+		return err
+	}
+
+	if c.UsageThresholdPercent < 0 || c.UsageThresholdPercent > 100 {
+		return fmt.Errorf("invalid usage_threshold_percent: %d is not in range 0-100", c.UsageThresholdPercent)
+	}
+
+	if _, err := c.HealthPolicy.Policy(); err != nil {
+		return fmt.Errorf("invalid health_policy: %v", err)
+	}
+
+	if c.HealthCheck != nil {
+		if err := validateHealthCheckConfig(c.HealthCheck); err != nil {
+			return fmt.Errorf("invalid health_check: %v", err)
+		}
+	}
+
+	if c.Quota != nil {
+		if err := validateQuotaConfig(c.Quota); err != nil {
+			return fmt.Errorf("invalid quota: %v", err)
+		}
+	}
+
+	if c.ReadinessCheck != nil {
+		if err := validateReadinessCheck(c.ReadinessCheck); err != nil {
+			return fmt.Errorf("invalid readiness_check: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -134,6 +335,8 @@ type DeploymentState struct {
 	shouldCleanup bool
 
 	sg                *terminal.StepGroup
+	source            *component.Source
+	deployConfig      *component.DeploymentConfig
 	client            *cloudfoundry.Client
 	space             *resources.Space
 	org               *resources.Organization
@@ -167,16 +370,44 @@ type DeploymentState struct {
 // - *component.LabelSet
 
 func (p *Platform) Deploy(
-	_ context.Context,
+	ctx context.Context,
 	log hclog.Logger,
 	src *component.Source,
 	img *docker.Image,
-	_ *component.DeploymentConfig,
+	deployConfig *component.DeploymentConfig,
+	ui terminal.UI,
+) (*Deployment, error) {
+	// Secrets known before the deployment, the other ones are added once they are read
+	p.redactor = utils.NewRedactor()
+	p.redactor.AddExplicit(os.Getenv(dockerPasswordEnv))
+	for k, v := range p.config.Env {
+		p.addEnvSecret(k, v, false)
+	}
+
+	deployment, err := p.deploy(
+		ctx,
+		utils.NewRedactingLogger(log, p.redactor),
+		src,
+		img,
+		deployConfig,
+		utils.NewRedactingUI(ui, p.redactor),
+	)
+	return deployment, p.redactor.RedactError(err)
+}
+
+func (p *Platform) deploy(
+	ctx context.Context,
+	log hclog.Logger,
+	src *component.Source,
+	img *docker.Image,
+	deployConfig *component.DeploymentConfig,
 	ui terminal.UI,
 ) (*Deployment, error) {
 	state := DeploymentState{
-		img:        img,
-		deployment: &Deployment{},
+		img:          img,
+		source:       src,
+		deployConfig: deployConfig,
+		deployment:   &Deployment{},
 	}
 
 	var err error
@@ -217,6 +448,8 @@ func (p *Platform) Deploy(
 	if err != nil {
 		return nil, err
 	}
+	// The first requests may have refreshed the token
+	p.redactor.AddExplicit(state.client.Tokens()...)
 
 	state.space = &space
 	state.org = &org
@@ -247,13 +480,37 @@ func (p *Platform) Deploy(
 		return nil, err
 	}
 	state.deployment.AppGUID = state.app.GUID
+	// Deferred after cleanupResourcesOnFail, so that it runs before the app is deleted
+	defer p.outputAppEventsOnFail(&state, ui)
+
+	err = p.configurePorts(&state)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.configureProcesses(&state)
+	if err != nil {
+		return nil, err
+	}
 
 	err = p.configureQuota(state)
 	if err != nil {
 		return nil, err
 	}
 
-	state.cfPackage, err = p.createPackage(state)
+	// The health checks are configured before the droplet is started, so that the first
+	// instances are already checked by waitProcess
+	err = p.configureHealthCheck(&state)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.configureReadinessCheck(&state)
+	if err != nil {
+		return nil, err
+	}
+
+	state.cfPackage, err = p.createPackage(ctx, state)
 	if err != nil {
 		return nil, err
 	}
@@ -283,12 +540,12 @@ func (p *Platform) Deploy(
 		return nil, err
 	}
 
-	err = p.configureHealthCheck(&state)
+	err = p.bindRoute(&state)
 	if err != nil {
 		return nil, err
 	}
 
-	err = p.bindRoute(&state)
+	err = p.createNetworkPolicies(&state)
 	if err != nil {
 		return nil, err
 	}
@@ -297,6 +554,9 @@ func (p *Platform) Deploy(
 
 	log.Debug("route_url", "url", state.route.URL)
 	state.deployment.Url = state.route.URL
+	if state.route.Protocol == "tcp" {
+		state.deployment.Url = fmt.Sprintf("tcp://%v", state.route.URL)
+	}
 
 	return state.deployment, nil
 }
@@ -320,6 +580,21 @@ func (p *Platform) createApp(state *DeploymentState) (*resources.Application, er
 	return &app, nil
 }
 
+func (p *Platform) configurePorts(state *DeploymentState) error {
+	if len(p.config.Ports) == 0 {
+		return nil
+	}
+
+	step := (*state.sg).Add(fmt.Sprintf("Configuring app ports %v", p.config.Ports))
+	err := state.client.UpdateApplicationPorts(state.app.GUID, p.config.Ports)
+	if err != nil {
+		step.Abort()
+		return fmt.Errorf("failed to set app ports: %v", err)
+	}
+	step.Done()
+	return nil
+}
+
 func (p *Platform) Generation() ([]byte, error) {
 	return uuid.New().MarshalBinary()
 }
@@ -329,30 +604,44 @@ func (p *Platform) GenerationFunc() interface{} {
 	return p.Generation
 }
 
-func (p *Platform) createPackage(state DeploymentState) (*resources.Package, error) {
-	step := (*state.sg).Add(fmt.Sprintf("Creating new package for docker image %s:%s in app",
-		state.img.Image,
-		state.img.Tag,
-	))
+func (p *Platform) createPackage(ctx context.Context, state DeploymentState) (*resources.Package, error) {
+	image := utils.ImageReference(state.img.Image, state.img.Tag)
+	step := (*state.sg).Add(fmt.Sprintf("Creating new package for docker image %s in app", image))
 	dockerPackage := resources.Package{
 		Type:        constant.PackageTypeDocker,
-		DockerImage: fmt.Sprintf("%s:%s", state.img.Image, state.img.Tag),
+		DockerImage: image,
 		Relationships: resources.Relationships{
 			constant.RelationshipTypeApplication: resources.Relationship{GUID: state.deployment.AppGUID},
 		},
 	}
 
-	// Docker pull credentials
-	if p.config.Docker != nil {
-		// Using docker credentials
-		dockerPackage.DockerUsername = p.config.Docker.Username
+	// Docker pull credentials, none for public images
+	credentials, source, err := p.dockerCredentials(state.img.Image)
+	if err != nil {
+		step.Abort()
+		return nil, err
+	}
+	if credentials != nil {
+		p.log.Debug("using docker credentials", "username", credentials.Username, "source", source)
+		dockerPackage.DockerUsername = credentials.Username
+		dockerPackage.DockerPassword = credentials.Password
+	}
 
-		// Docker password from env variable
-		dockerPackage.DockerPassword = os.Getenv("CF_DOCKER_PASSWORD")
-		if dockerPackage.DockerPassword == "" {
+	// The digest pins the image, so that instances restarted later don't run a re-pushed tag
+	if p.config.PinImageDigest {
+		step.Update(fmt.Sprintf("Resolving digest of docker image %s", image))
+		opts := utils.DigestOptions{Insecure: p.config.Docker != nil && p.config.Docker.Insecure}
+		if credentials != nil {
+			opts.Username, opts.Password = credentials.Username, credentials.Password
+		}
+		digest, err := utils.ResolveImageDigest(ctx, state.img.Image, state.img.Tag, opts)
+		if err != nil {
 			step.Abort()
-			return nil, fmt.Errorf("invalid docker credentials: %s", errDockerPasswordEmpty)
+			return nil, fmt.Errorf("unable to resolve digest of %s: %v", image, err)
 		}
+		state.deployment.ImageDigest = digest
+		dockerPackage.DockerImage = utils.ImageReference(state.img.Image, digest)
+		step.Update(fmt.Sprintf("Creating new package for docker image %s in app", dockerPackage.DockerImage))
 	}
 
 	cfPackage, err := state.client.CreatePackage(dockerPackage)
@@ -373,6 +662,7 @@ func (p *Platform) connectCloudFoundry(state *DeploymentState) error {
 	}
 
 	state.client = client
+	p.redactor.AddExplicit(client.Tokens()...)
 
 	step.Update(fmt.Sprintf("Connecting to Cloud Foundry at %s", client.CloudControllerURL()))
 	step.Done()
@@ -442,6 +732,13 @@ func (p *Platform) configureHealthCheck(state *DeploymentState) error {
 		}
 
 		for _, process := range processes {
+			// The other processes aren't routed, they keep the process health check of the manifest
+			if process.Type != constant.ProcessTypeWeb {
+				continue
+			}
+
+			// Listed commands are hidden, sending them back would replace the command of the process
+			process.Command = types.FilteredString{}
 			process.HealthCheckType = state.healthCheckParams.Type
 
 			if state.healthCheckParams.Endpoint != "" {
@@ -456,7 +753,7 @@ func (p *Platform) configureHealthCheck(state *DeploymentState) error {
 				process.HealthCheckTimeout = state.healthCheckParams.Timeout
 			}
 
-			p.log.Debug("updating process: %v", process)
+			p.log.Debug("updating process", "guid", process.GUID, "type", process.Type)
 
 			_, err = state.client.UpdateApplicationProcess(process)
 			if err != nil {
@@ -517,43 +814,78 @@ func (p *Platform) validateQuota(state *DeploymentState) error {
 	return nil
 }
 
+// validateQuotaConfig checks that the memory and disk quotas are valid quantities
+func validateQuotaConfig(quota *QuotaConfig) error {
+	if quota.Memory != "" {
+		if _, err := parseQuantity(quota.Memory); err != nil {
+			return fmt.Errorf("unable to parse memory: %v", err)
+		}
+	}
+	if quota.Disk != "" {
+		if _, err := parseQuantity(quota.Disk); err != nil {
+			return fmt.Errorf("unable to parse disk: %v", err)
+		}
+	}
+	return nil
+}
+
 func (p *Platform) validateHealthCheck(state *DeploymentState) error {
 	p.log.Debug("validate health check")
 
 	step := (*state.sg).Add("Validating health check parameters")
-	state.healthCheckParams = &HealthCheckParams{}
 
 	if p.config.HealthCheck != nil {
 		p.log.Debug("health check config is not nil")
 
-		state.healthCheckParams.Type = constant.HealthCheckType(p.config.HealthCheck.Type)
-
-		if state.healthCheckParams.Type == constant.HTTP && p.config.HealthCheck.Endpoint == "" {
+		if err := validateHealthCheckConfig(p.config.HealthCheck); err != nil {
 			step.Abort()
-			return fmt.Errorf("undefined endpoint for HTTP health check")
+			return err
 		}
-		state.healthCheckParams.Endpoint = p.config.HealthCheck.Endpoint
 
-		if p.config.HealthCheck.InvocationTimeout < 0 || p.config.HealthCheck.InvocationTimeout > 180 {
-			step.Abort()
-			return fmt.Errorf("invocation timeout has to be 0-180s")
+		state.healthCheckParams = &HealthCheckParams{
+			Type:              constant.HealthCheckType(p.config.HealthCheck.Type),
+			Endpoint:          p.config.HealthCheck.Endpoint,
+			InvocationTimeout: p.config.HealthCheck.InvocationTimeout,
+			Timeout:           p.config.HealthCheck.Timeout,
 		}
-		state.healthCheckParams.InvocationTimeout = p.config.HealthCheck.InvocationTimeout
-
-		if p.config.HealthCheck.Timeout < 0 || p.config.HealthCheck.Timeout > 180 {
-			step.Abort()
-			return fmt.Errorf("timeout has to be 0-180s")
+		if state.healthCheckParams.Type != constant.HTTP && state.healthCheckParams.Endpoint != "" {
+			p.log.Warn("endpoint is only used by HTTP health checks, ignoring it", "type", state.healthCheckParams.Type)
+			state.healthCheckParams.Endpoint = ""
 		}
-		state.healthCheckParams.Timeout = p.config.HealthCheck.Timeout
-
-		p.log.Debug("health check params: %v", state.healthCheckParams)
 
+		p.log.Debug("health check params",
+			"type", state.healthCheckParams.Type,
+			"endpoint", state.healthCheckParams.Endpoint,
+		)
 	}
 
 	step.Done()
 	return nil
 }
 
+// validateHealthCheckConfig checks the health check against the values accepted by Cloud Foundry
+func validateHealthCheckConfig(healthCheck *HealthCheckConfig) error {
+	switch constant.HealthCheckType(healthCheck.Type) {
+	case constant.HTTP:
+		if healthCheck.Endpoint == "" {
+			return fmt.Errorf("undefined endpoint for HTTP health check")
+		}
+	case constant.Port, constant.Process:
+		// An endpoint is ignored, validateHealthCheck warns about it
+	default:
+		return fmt.Errorf("health check type has to be port, process or http, got %q", healthCheck.Type)
+	}
+
+	if healthCheck.InvocationTimeout < 0 || healthCheck.InvocationTimeout > 180 {
+		return fmt.Errorf("invocation timeout has to be 0-180s")
+	}
+
+	if healthCheck.Timeout < 0 || healthCheck.Timeout > 180 {
+		return fmt.Errorf("timeout has to be 0-180s")
+	}
+	return nil
+}
+
 func (p *Platform) searchApp(state *DeploymentState) error {
 	step := (*state.sg).Add(fmt.Sprintf("Searching app: %s", state.deployment.Name))
 	var err error
@@ -621,7 +953,7 @@ func (p *Platform) createBuild(state *DeploymentState) error {
 
 	state.cfBuild = &cfBuild
 
-	p.log.Debug("build created", "cfbuild", fmt.Sprintf("%+v", cfBuild))
+	p.log.Debug("build created", "guid", cfBuild.GUID, "state", cfBuild.State)
 
 	// Wait for droplet to become ready
 	for {
@@ -634,7 +966,7 @@ func (p *Platform) createBuild(state *DeploymentState) error {
 
 		time.Sleep(500 * time.Millisecond)
 		build, _ := state.client.GetBuild(state.cfBuild.GUID)
-		p.log.Debug("build update", "build", fmt.Sprintf("%+v", build))
+		p.log.Debug("build update", "guid", build.GUID, "state", build.State)
 		state.cfBuild = &build
 		step.Update(
 			fmt.Sprintf("Creating a new build for the created package of image %v [%v]",
@@ -659,8 +991,7 @@ func (p *Platform) createDeployment(state *DeploymentState) error {
 }
 
 func (p *Platform) bindRoute(state *DeploymentState) error {
-	routeUrl := fmt.Sprintf("%v.%v", state.deployment.Name, p.config.Domain)
-	step := (*state.sg).Add(fmt.Sprintf("Binding route %v to application", routeUrl))
+	step := (*state.sg).Add(fmt.Sprintf("Binding route on domain %v to application", p.config.Domain))
 	domains, err := state.client.GetDomains(p.config.Domain)
 	if err != nil || len(domains) == 0 {
 		step.Abort()
@@ -668,30 +999,57 @@ func (p *Platform) bindRoute(state *DeploymentState) error {
 	}
 	domain := domains[0]
 
-	if len(p.config.ServiceBindings) > 0 {
-
-	}
-
-	route, err := state.client.CreateRoute(resources.Route{
+	routeRequest := resources.Route{
 		DomainGUID: domain.GUID,
 		SpaceGUID:  state.deployment.SpaceGUID,
-		Host:       state.deployment.Name,
-		Destinations: []resources.RouteDestination{{
-			App: resources.RouteDestinationApp{
-				GUID: state.deployment.AppGUID,
-			},
-		}},
-	})
+	}
 
+	if cloudfoundry.IsTCPDomain(domain) {
+		// TCP routes have no host and path, a random port is assigned if route_port is not set
+		if p.config.RoutePath != "" || p.config.RouteLoadBalancing != "" || p.config.RouteProtocol != "" {
+			step.Abort()
+			return fmt.Errorf(
+				"route_path, route_loadbalancing and route_protocol cannot be used with TCP domain %v",
+				domain.Name,
+			)
+		}
+		routeRequest.Port = p.config.RoutePort
+	} else {
+		if p.config.RoutePort != 0 {
+			step.Abort()
+			return fmt.Errorf("route_port can only be used with TCP domains, %v is an HTTP domain", domain.Name)
+		}
+		routeRequest.Host = state.deployment.Name
+		routeRequest.Path = p.config.RoutePath
+	}
+
+	route, err := state.client.CreateRoute(routeRequest)
 	if err != nil {
 		step.Abort()
 		return fmt.Errorf("failed to create route: %v", err)
 	}
 
 	state.route = &route
+	state.deployment.RouteGUID = route.GUID
+	routeUrl := utils.RouteURL(route.Host, domain.Name, route.Path, route.Port)
+	step.Update(fmt.Sprintf("Binding route %v to application", routeUrl))
+
+	if p.config.RouteLoadBalancing != "" {
+		err = state.client.UpdateRouteOptions(route.GUID, cloudfoundry.RouteOptions{
+			LoadBalancing: p.config.RouteLoadBalancing,
+		})
+		if err != nil {
+			step.Abort()
+			return fmt.Errorf("failed to set route options: %v", err)
+		}
+	}
 
-	// Also map
-	err = state.client.MapRoute(route.GUID, state.deployment.AppGUID)
+	state.deployment.AppPort = int32(p.appPort())
+	err = state.client.MapRouteDestination(route.GUID, cloudfoundry.RouteDestination{
+		AppGUID:  state.deployment.AppGUID,
+		Port:     p.appPort(),
+		Protocol: p.config.RouteProtocol,
+	})
 	if err != nil {
 		step.Abort()
 		return fmt.Errorf("failed to map route: %v", err)
@@ -700,31 +1058,88 @@ func (p *Platform) bindRoute(state *DeploymentState) error {
 	return nil
 }
 
+// appPort returns the app port the routes are mapped to: app_port if set, otherwise the first
+// of the configured ports. If 0, CF uses the default port of the app.
+func (p *Platform) appPort() int {
+	if p.config.AppPort != 0 {
+		return p.config.AppPort
+	}
+	if len(p.config.Ports) > 0 {
+		return p.config.Ports[0]
+	}
+	return 0
+}
+
 func (p *Platform) setEnvironmentVariables(state *DeploymentState) error {
+	var entrypointEnv map[string]string
+	if state.deployConfig != nil && !p.config.DisableEntrypointEnv {
+		entrypointEnv = state.deployConfig.Env()
+	}
+
 	// Set environment variables to app
-	if len(p.config.Env) != 0 || p.config.EnvFromFile != "" {
+	if len(p.config.Env) != 0 || len(p.config.EnvFromFile) != 0 || len(entrypointEnv) != 0 {
 		step := (*state.sg).Add("Assigning environment variables")
-		envVars := resources.EnvironmentVariables{}
 
-		// Precedence: envFromFile, env
-		if p.config.EnvFromFile != "" {
-			step := (*state.sg).Add("Adding environment variables from file")
-			envContent := utils.ParseEnv(p.config.EnvFromFile)
-			for k, v := range envContent {
-				addFilteredEnvVar(envVars, k, v)
+		// Precedence: the Waypoint entrypoint configuration, the files of envFromFile in order, then env
+		var layers []utils.EnvLayer
+		fileSources := map[string]bool{}
+		if len(entrypointEnv) != 0 {
+			layers = append(layers, utils.EnvLayer{Source: "waypoint entrypoint", Env: entrypointEnv})
+		}
+		for _, file := range p.config.EnvFromFile {
+			fileStep := (*state.sg).Add(fmt.Sprintf("Adding environment variables from %v", file))
+			path := file
+			if !filepath.IsAbs(path) && state.source != nil {
+				path = filepath.Join(state.source.Path, path)
 			}
-			step.Done()
+
+			// Variables can refer to the ones of the previous files, or to the environment of the runner
+			merged, _ := utils.MergeEnv(layers...)
+			lookup := func(name string) (string, bool) {
+				if v, ok := merged[name]; ok {
+					return v, true
+				}
+				return os.LookupEnv(name)
+			}
+
+			envContent, err := utils.ReadEnvFile(path, lookup)
+			if err != nil {
+				fileStep.Abort()
+				step.Abort()
+				return fmt.Errorf("unable to read env_from_file: %v", err)
+			}
+			layers = append(layers, utils.EnvLayer{Source: file, Env: envContent})
+			fileSources[file] = true
+			fileStep.Done()
 		}
 
 		if len(p.config.Env) != 0 {
-			step := (*state.sg).Add("Adding environment variables from HCL")
-			for k, v := range p.config.Env {
-				addFilteredEnvVar(envVars, k, v)
-			}
-			step.Done()
+			layers = append(layers, utils.EnvLayer{Source: "env", Env: p.config.Env})
+		}
+
+		env, sources := utils.MergeEnv(layers...)
+		for k, v := range env {
+			p.log.Debug("environment variable", "key", k, "source", sources[k], "value", utils.MaskValue(v))
 		}
 
-		_, err := state.client.UpdateApplicationEnvironmentVariables(state.app.GUID, envVars)
+		err := p.resolveCredHubReferences(state, env)
+		if err != nil {
+			step.Abort()
+			return err
+		}
+
+		// Secrets are never shown. The values of env files are secrets, as these files are
+		// often kept out of the repository for that reason
+		for k, v := range env {
+			p.addEnvSecret(k, v, fileSources[sources[k]])
+		}
+
+		envVars := resources.EnvironmentVariables{}
+		for k, v := range env {
+			addFilteredEnvVar(envVars, k, v)
+		}
+
+		_, err = state.client.UpdateApplicationEnvironmentVariables(state.app.GUID, envVars)
 		if err != nil {
 			step.Abort()
 			return fmt.Errorf("unable to set environment variables: %v", err)
@@ -734,6 +1149,20 @@ func (p *Platform) setEnvironmentVariables(state *DeploymentState) error {
 	return nil
 }
 
+// addEnvSecret masks the value of an env variable if it's a secret. The values of sensitive_env are
+// always masked, the ones guessed from the name of the variable or read from a file if long enough
+func (p *Platform) addEnvSecret(key, value string, fromFile bool) {
+	for _, sensitive := range p.config.SensitiveEnv {
+		if sensitive == key {
+			p.redactor.AddExplicit(value)
+			return
+		}
+	}
+	if fromFile || utils.IsSensitiveKey(key) {
+		p.redactor.Add(value)
+	}
+}
+
 func (p *Platform) bindServices(state *DeploymentState) error {
 	if len(p.config.ServiceBindings) > 0 {
 		step := (*state.sg).Add("Binding services")
@@ -754,8 +1183,8 @@ func (p *Platform) bindServices(state *DeploymentState) error {
 
 			// bind service
 			p.log.Debug("create service binding",
-				"serviceInstance", serviceInstance,
-				"app", state.app,
+				"serviceInstance", serviceInstance.Name,
+				"app", state.app.Name,
 				"serviceInstanceGUID", serviceInstance.GUID,
 				"appGUID", state.app.GUID,
 			)
@@ -799,44 +1228,56 @@ func (p *Platform) waitProcess(state *DeploymentState) error {
 	}
 
 	startTime := time.Now()
+	var instances [][]ccv3.ProcessInstance
 	for {
-		processes := map[resources.Process][]ccv3.ProcessInstance{}
 		if time.Now().After(startTime.Add(p.config.deploymentTimeout)) {
 			return fmt.Errorf(
 				"timeout: %.0f seconds elapsed but the application isn't started yet. "+
-					"deployment=%v, processes=%v",
+					"app=%v (%v), instances=%v",
 				p.config.deploymentTimeout.Seconds(),
-				state.deployment,
-				processes,
+				state.deployment.Name,
+				state.deployment.AppGUID,
+				describeInstances(applicationProcesses, instances),
 			)
 		}
 
-		for _, proc := range applicationProcesses {
-			procInstances, err := state.client.GetProcessInstances(proc.GUID)
-			if err != nil {
-				return fmt.Errorf("unable to get process instances for process %v", proc)
-			}
-			processes[proc] = procInstances
+		instances, err = state.client.GetProcessesInstances(applicationProcesses)
+		if err != nil {
+			return fmt.Errorf("unable to get process instances: %v", err)
+		}
+
+		readiness, err := p.processesReadiness(state, applicationProcesses)
+		if err != nil {
+			return err
 		}
 
 		starting := false
 
 		// Check status
-		for process, processInstances := range processes {
-			for _, instance := range processInstances {
+		for i, process := range applicationProcesses {
+			for _, instance := range instances[i] {
 				switch instance.State {
 				case constant.ProcessInstanceCrashed:
-					return fmt.Errorf("deployment failed: process crashed, process=%v, processInstance=%v",
-						process, instance,
+					reason := p.crashReason(state, instance)
+					if reason == "" {
+						reason = "no crash event found"
+					}
+					return fmt.Errorf("deployment failed: process %v crashed, %v, process=%v, instance=%v",
+						process.Type, reason, process.GUID, instance.Index,
 					)
 				case constant.ProcessInstanceStarting:
 					starting = true
+				case constant.ProcessInstanceRunning:
+					// Running instances aren't started until they pass their readiness check
+					if readiness != nil && isNotRoutable(readiness[i], instance.Index) {
+						starting = true
+					}
 				}
 			}
 		}
 
 		if !starting {
-			p.log.Info("processes are ready!", "processes", processes)
+			p.log.Info("processes are ready!", "instances", describeInstances(applicationProcesses, instances))
 			return nil
 		}
 
@@ -844,6 +1285,22 @@ func (p *Platform) waitProcess(state *DeploymentState) error {
 	}
 }
 
+// describeInstances summarizes the instance states of processes, e.g. "web=[RUNNING STARTING]".
+// Processes and instances aren't printed whole, as they contain the command of the app.
+func describeInstances(processes []resources.Process, instances [][]ccv3.ProcessInstance) string {
+	var descriptions []string
+	for i, process := range processes {
+		var states []string
+		if i < len(instances) {
+			for _, instance := range instances[i] {
+				states = append(states, string(instance.State))
+			}
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%v=%v", process.Type, states))
+	}
+	return strings.Join(descriptions, ", ")
+}
+
 func (p *Platform) parseTimeout() error {
 	if p.config.DeploymentTimeoutSeconds == "" {
 		// User didn't provide any timeout, using the default
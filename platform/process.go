@@ -0,0 +1,117 @@
+package platform
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3/constant"
+	"gopkg.in/yaml.v3"
+)
+
+// validateProcesses checks the command and the process blocks, each process type can only be configured once
+func validateProcesses(c *Config) error {
+	configured := map[string]bool{}
+	if c.Command != "" {
+		configured[constant.ProcessTypeWeb] = true
+	}
+
+	for _, process := range c.Processes {
+		if process.Type == "" {
+			return fmt.Errorf("invalid process: type is required")
+		}
+		if process.Command == "" {
+			return fmt.Errorf("invalid process %s: command is required", process.Type)
+		}
+		if process.Instances != nil && *process.Instances < 0 {
+			return fmt.Errorf("invalid process %s: instances cannot be negative", process.Type)
+		}
+		if process.Type == constant.ProcessTypeWeb && process.Instances != nil {
+			return fmt.Errorf("invalid process web: the instances of web are set by quota.instances")
+		}
+		if configured[process.Type] {
+			if process.Type == constant.ProcessTypeWeb && c.Command != "" {
+				return fmt.Errorf("invalid process web: command and the web process block are exclusive")
+			}
+			return fmt.Errorf("invalid process %s: the process is configured twice", process.Type)
+		}
+		configured[process.Type] = true
+	}
+	return nil
+}
+
+type manifestProcess struct {
+	Type            string `yaml:"type"`
+	Command         string `yaml:"command"`
+	Instances       *int   `yaml:"instances,omitempty"`
+	Memory          string `yaml:"memory,omitempty"`
+	DiskQuota       string `yaml:"disk_quota,omitempty"`
+	HealthCheckType string `yaml:"health-check-type,omitempty"`
+}
+
+type manifestApplication struct {
+	Name      string            `yaml:"name"`
+	Processes []manifestProcess `yaml:"processes"`
+}
+
+type manifest struct {
+	Applications []manifestApplication `yaml:"applications"`
+}
+
+// processesManifest returns the app manifest setting the configured process commands, or nil if
+// no command is configured. The processes other than web get the memory and disk of the quota, which
+// configureQuota sets for web. They aren't routed, so their health check defaults to process instead of port.
+func (p *Platform) processesManifest(appName string, quota *QuotaParams) ([]byte, error) {
+	var processes []manifestProcess
+	if p.config.Command != "" {
+		processes = append(processes, manifestProcess{Type: constant.ProcessTypeWeb, Command: p.config.Command})
+	}
+	for _, process := range p.config.Processes {
+		mp := manifestProcess{Type: process.Type, Command: process.Command}
+		if process.Type != constant.ProcessTypeWeb {
+			instances := 1
+			if process.Instances != nil {
+				instances = *process.Instances
+			}
+			mp.Instances = &instances
+			if quota != nil && quota.memoryMb != 0 {
+				mp.Memory = fmt.Sprintf("%dM", quota.memoryMb)
+			}
+			if quota != nil && quota.diskMb != 0 {
+				mp.DiskQuota = fmt.Sprintf("%dM", quota.diskMb)
+			}
+			mp.HealthCheckType = string(constant.Process)
+		}
+		processes = append(processes, mp)
+	}
+	if len(processes) == 0 {
+		return nil, nil
+	}
+
+	return yaml.Marshal(manifest{
+		Applications: []manifestApplication{{Name: appName, Processes: processes}},
+	})
+}
+
+// configureProcesses sets the start commands of the processes before the droplet is staged, so that
+// they replace the entrypoint and command of the image. Missing processes are created with a manifest,
+// as Docker apps only have a web process.
+func (p *Platform) configureProcesses(state *DeploymentState) error {
+	rawManifest, err := p.processesManifest(state.app.Name, state.quotaParams)
+	if err != nil {
+		return fmt.Errorf("unable to create the processes manifest: %v", err)
+	}
+	if rawManifest == nil {
+		return nil
+	}
+
+	step := (*state.sg).Add("Configuring process commands...")
+	p.log.Debug("applying processes manifest", "app", state.app.Name)
+
+	err = state.client.ApplySpaceManifest(state.space.GUID, rawManifest)
+	if err != nil {
+		step.Abort()
+		return fmt.Errorf("unable to configure process commands: %v", err)
+	}
+
+	step.Done()
+	return nil
+}
@@ -0,0 +1,184 @@
+package platform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/hashicorp/waypoint/builtin/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry/cftest"
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidateProcesses(t *testing.T) {
+	instances := func(n int) *int {
+		return &n
+	}
+
+	for _, tc := range []struct {
+		name   string
+		config Config
+		err    string
+	}{
+		{name: "none"},
+		{
+			name: "valid",
+			config: Config{Command: "./server", Processes: []*ProcessConfig{
+				{Type: "worker", Command: "./worker", Instances: instances(2)},
+			}},
+		},
+		{
+			name:   "web block",
+			config: Config{Processes: []*ProcessConfig{{Type: "web", Command: "./server"}}},
+		},
+		{
+			name:   "missing type",
+			config: Config{Processes: []*ProcessConfig{{Command: "./worker"}}},
+			err:    "invalid process: type is required",
+		},
+		{
+			name:   "missing command",
+			config: Config{Processes: []*ProcessConfig{{Type: "worker"}}},
+			err:    "invalid process worker: command is required",
+		},
+		{
+			name:   "stopped",
+			config: Config{Processes: []*ProcessConfig{{Type: "worker", Command: "./worker", Instances: instances(0)}}},
+		},
+		{
+			name:   "negative instances",
+			config: Config{Processes: []*ProcessConfig{{Type: "worker", Command: "./worker", Instances: instances(-1)}}},
+			err:    "invalid process worker: instances cannot be negative",
+		},
+		{
+			name:   "web instances",
+			config: Config{Processes: []*ProcessConfig{{Type: "web", Command: "./server", Instances: instances(2)}}},
+			err:    "invalid process web: the instances of web are set by quota.instances",
+		},
+		{
+			name:   "command and web block",
+			config: Config{Command: "./server", Processes: []*ProcessConfig{{Type: "web", Command: "./server"}}},
+			err:    "invalid process web: command and the web process block are exclusive",
+		},
+		{
+			name: "duplicate",
+			config: Config{Processes: []*ProcessConfig{
+				{Type: "worker", Command: "./worker"},
+				{Type: "worker", Command: "./other"},
+			}},
+			err: "invalid process worker: the process is configured twice",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateProcesses(&tc.config)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestDeployConfiguresProcesses(t *testing.T) {
+	var manifestRequest []byte
+	var processRequests []string
+	server := fakeCloudController(t, false, func(route string, body []byte) {
+		switch route {
+		case "POST /v3/spaces/space-guid/actions/apply_manifest":
+			manifestRequest = body
+		case "PATCH /v3/processes/process-guid":
+			processRequests = append(processRequests, string(body))
+		}
+	})
+	defer server.Close()
+	cftest.Target(t, server, fakeAccessToken, fakeRefreshToken)
+	t.Setenv(dockerConfigEnv, t.TempDir())
+
+	stopped, one := 0, 1
+	p := Platform{config: Config{
+		Organisation: "org",
+		Space:        "space",
+		Domain:       "example.com",
+		Command:      "./server --port 8080",
+		Processes: []*ProcessConfig{
+			{Type: "worker", Command: "./worker"},
+			{Type: "scheduler", Command: "./scheduler", Instances: &stopped},
+		},
+		Quota:       &QuotaConfig{Memory: "512Mi", Disk: "1Gi"},
+		HealthCheck: &HealthCheckConfig{Type: "process"},
+	}}
+
+	_, err := p.Deploy(
+		context.Background(),
+		hclog.NewNullLogger(),
+		&component.Source{App: "app"},
+		&docker.Image{Image: "registry.example.com/app", Tag: "1.0"},
+		&component.DeploymentConfig{},
+		terminal.NonInteractiveUI(context.Background()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var applied manifest
+	assert.NoError(t, yaml.Unmarshal(manifestRequest, &applied))
+	assert.Equal(t, manifest{Applications: []manifestApplication{{
+		Name: "app",
+		Processes: []manifestProcess{
+			{Type: "web", Command: "./server --port 8080"},
+			{Type: "worker", Command: "./worker", Instances: &one, Memory: "512M", DiskQuota: "1024M", HealthCheckType: "process"},
+			{Type: "scheduler", Command: "./scheduler", Instances: &stopped, Memory: "512M", DiskQuota: "1024M", HealthCheckType: "process"},
+		},
+	}}}, applied)
+
+	// The health check doesn't send back the hidden command of the listed process
+	assert.Len(t, processRequests, 1)
+	assert.NotContains(t, processRequests[0], "command")
+}
+
+func TestDeployKeepsWorkerHealthCheck(t *testing.T) {
+	var webRequests, workerRequests []string
+	server := fakeCloudController(t, false, func(route string, body []byte) {
+		switch route {
+		case "PATCH /v3/processes/process-guid":
+			webRequests = append(webRequests, string(body))
+		case "PATCH /v3/processes/worker-guid":
+			workerRequests = append(workerRequests, string(body))
+		}
+	})
+	defer server.Close()
+	cftest.Target(t, server, fakeAccessToken, fakeRefreshToken)
+	t.Setenv(dockerConfigEnv, t.TempDir())
+
+	p := Platform{config: Config{
+		Organisation:   "org",
+		Space:          "space",
+		Domain:         "example.com",
+		Processes:      []*ProcessConfig{{Type: "worker", Command: "./worker"}},
+		HealthCheck:    &HealthCheckConfig{Type: "http", Endpoint: "/health"},
+		ReadinessCheck: &ReadinessCheckConfig{Type: "http", Endpoint: "/ready"},
+	}}
+
+	_, err := p.Deploy(
+		context.Background(),
+		hclog.NewNullLogger(),
+		&component.Source{App: "app"},
+		&docker.Image{Image: "registry.example.com/app", Tag: "1.0"},
+		&component.DeploymentConfig{},
+		terminal.NonInteractiveUI(context.Background()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The worker isn't routed, it keeps the process health check set by the manifest
+	assert.Empty(t, workerRequests)
+	if assert.Len(t, webRequests, 2) {
+		assert.Contains(t, webRequests[0], `"endpoint":"/health"`)
+		assert.Contains(t, webRequests[1], `"readiness_health_check"`)
+	}
+}
@@ -0,0 +1,95 @@
+package platform
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3/constant"
+	"code.cloudfoundry.org/cli/resources"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry"
+)
+
+func validateReadinessCheck(check *ReadinessCheckConfig) error {
+	switch check.Type {
+	case "http":
+		if check.Endpoint == "" {
+			return fmt.Errorf("endpoint is required for http readiness checks")
+		}
+	case "port", "process":
+		// An endpoint is ignored like for the health check, configureReadinessCheck warns about it
+	default:
+		return fmt.Errorf("type has to be http, port or process, got %q", check.Type)
+	}
+
+	if check.InvocationTimeout < 0 || check.InvocationTimeout > 180 {
+		return fmt.Errorf("invocation timeout has to be 0-180s")
+	}
+	if check.Interval < 0 {
+		return fmt.Errorf("interval cannot be negative")
+	}
+	return nil
+}
+
+// configureReadinessCheck sets the readiness health check of the web process before it is started,
+// so that instances only receive traffic once they are ready
+func (p *Platform) configureReadinessCheck(state *DeploymentState) error {
+	if p.config.ReadinessCheck == nil {
+		return nil
+	}
+
+	step := (*state.sg).Add("Configuring readiness check...")
+
+	processes, err := state.client.GetApplicationProcesses(state.deployment.AppGUID)
+	if err != nil {
+		step.Abort()
+		return fmt.Errorf("failed to get application processes: %v", err)
+	}
+
+	endpoint := p.config.ReadinessCheck.Endpoint
+	if p.config.ReadinessCheck.Type != "http" && endpoint != "" {
+		p.log.Warn("endpoint is only used by HTTP readiness checks, ignoring it", "type", p.config.ReadinessCheck.Type)
+		endpoint = ""
+	}
+
+	check := cloudfoundry.ReadinessHealthCheck{
+		Type: p.config.ReadinessCheck.Type,
+		Data: cloudfoundry.ReadinessHealthCheckData{
+			Endpoint:          endpoint,
+			InvocationTimeout: p.config.ReadinessCheck.InvocationTimeout,
+			Interval:          p.config.ReadinessCheck.Interval,
+		},
+	}
+	for _, process := range processes {
+		// Only web is routed
+		if process.Type != constant.ProcessTypeWeb {
+			continue
+		}
+		err = state.client.UpdateProcessReadinessHealthCheck(process.GUID, check)
+		if err != nil {
+			step.Abort()
+			return fmt.Errorf("unable to configure readiness check of process %v: %v", process.Type, err)
+		}
+	}
+
+	step.Done()
+	return nil
+}
+
+// processesReadiness returns, for each process, which of its instances pass their readiness check.
+// It returns nil if no readiness check is configured.
+func (p *Platform) processesReadiness(state *DeploymentState, processes []resources.Process) ([]map[int64]bool, error) {
+	if p.config.ReadinessCheck == nil {
+		return nil, nil
+	}
+
+	readiness, err := state.client.GetProcessesRoutable(processes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get readiness of the processes: %v", err)
+	}
+	return readiness, nil
+}
+
+// isNotRoutable tells whether an instance is known to fail its readiness check
+func isNotRoutable(readiness map[int64]bool, index int64) bool {
+	routable, ok := readiness[index]
+	return ok && !routable
+}
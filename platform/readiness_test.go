@@ -0,0 +1,211 @@
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/hashicorp/waypoint/builtin/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry/cftest"
+)
+
+func TestValidateReadinessCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		check ReadinessCheckConfig
+		err   string
+	}{
+		{name: "http", check: ReadinessCheckConfig{Type: "http", Endpoint: "/ready", InvocationTimeout: 180, Interval: 10}},
+		{name: "port", check: ReadinessCheckConfig{Type: "port"}},
+		{name: "process", check: ReadinessCheckConfig{Type: "process"}},
+		{name: "http without endpoint", check: ReadinessCheckConfig{Type: "http"}, err: "endpoint is required"},
+		{name: "endpoint of port check", check: ReadinessCheckConfig{Type: "port", Endpoint: "/ready"}},
+		{name: "unknown type", check: ReadinessCheckConfig{Type: "tcp"}, err: `got "tcp"`},
+		{
+			name:  "invocation timeout too long",
+			check: ReadinessCheckConfig{Type: "port", InvocationTimeout: 181},
+			err:   "invocation timeout has to be 0-180s",
+		},
+		{
+			name:  "negative interval",
+			check: ReadinessCheckConfig{Type: "port", Interval: -1},
+			err:   "interval cannot be negative",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateReadinessCheck(&tc.check)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+// TestConfigSetCheckEndpoint checks that the health and readiness checks handle an endpoint the same way:
+// it's required for http checks, and ignored by the other types
+func TestConfigSetCheckEndpoint(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		checkType string
+		endpoint  string
+		err       bool
+	}{
+		{name: "http", checkType: "http", endpoint: "/health"},
+		{name: "http without endpoint", checkType: "http", err: true},
+		{name: "port", checkType: "port"},
+		{name: "endpoint of port check", checkType: "port", endpoint: "/health"},
+		{name: "endpoint of process check", checkType: "process", endpoint: "/health"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var p Platform
+			healthCheckErr := p.ConfigSet(&Config{
+				HealthCheck: &HealthCheckConfig{Type: tc.checkType, Endpoint: tc.endpoint},
+			})
+			readinessCheckErr := p.ConfigSet(&Config{
+				ReadinessCheck: &ReadinessCheckConfig{Type: tc.checkType, Endpoint: tc.endpoint},
+			})
+			assert.Equal(t, tc.err, healthCheckErr != nil, "health check: %v", healthCheckErr)
+			assert.Equal(t, tc.err, readinessCheckErr != nil, "readiness check: %v", readinessCheckErr)
+		})
+	}
+}
+
+func TestDeployConfiguresReadinessCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		check    ReadinessCheckConfig
+		expected string
+	}{
+		{
+			name:  "http",
+			check: ReadinessCheckConfig{Type: "http", Endpoint: "/ready", InvocationTimeout: 5, Interval: 10},
+			expected: `{"readiness_health_check": {
+				"type": "http",
+				"data": {"endpoint": "/ready", "invocation_timeout": 5, "interval": 10}
+			}}`,
+		},
+		{
+			name:     "endpoint of port check",
+			check:    ReadinessCheckConfig{Type: "port", Endpoint: "/ready"},
+			expected: `{"readiness_health_check": {"type": "port", "data": {}}}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var processRequests []string
+			server := fakeCloudController(t, false, func(route string, body []byte) {
+				if route == "PATCH /v3/processes/process-guid" {
+					processRequests = append(processRequests, string(body))
+				}
+			})
+			defer server.Close()
+			cftest.Target(t, server, fakeAccessToken, fakeRefreshToken)
+			t.Setenv(dockerConfigEnv, t.TempDir())
+
+			check := tc.check
+			p := Platform{config: Config{
+				Organisation:   "org",
+				Space:          "space",
+				Domain:         "example.com",
+				ReadinessCheck: &check,
+			}}
+
+			_, err := p.Deploy(
+				context.Background(),
+				hclog.NewNullLogger(),
+				&component.Source{App: "app"},
+				&docker.Image{Image: "registry.example.com/app", Tag: "1.0"},
+				&component.DeploymentConfig{},
+				terminal.NonInteractiveUI(context.Background()),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if assert.Len(t, processRequests, 1) {
+				assert.JSONEq(t, tc.expected, processRequests[0])
+			}
+		})
+	}
+}
+
+// readinessCloudController serves the web process of app-guid, whose running instance isn't routable
+// until the stats were requested notRoutable times
+func readinessCloudController(t *testing.T, notRoutable int) *cloudfoundry.Client {
+	var mu sync.Mutex
+	statsRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch route := r.Method + " " + r.URL.Path; route {
+		case "POST /oauth/token":
+			response = map[string]interface{}{
+				"access_token":  fakeAccessToken,
+				"refresh_token": fakeRefreshToken,
+				"token_type":    "bearer",
+			}
+		case "GET /v3/apps/app-guid/processes":
+			response = cftest.List(map[string]interface{}{"guid": "process-guid", "type": "web", "instances": 1})
+		case "GET /v3/processes/process-guid/stats":
+			mu.Lock()
+			statsRequests++
+			routable := statsRequests > notRoutable
+			mu.Unlock()
+			response = cftest.List(map[string]interface{}{
+				"type": "web", "index": 0, "state": "RUNNING", "uptime": 1, "routable": routable,
+			})
+		default:
+			t.Errorf("unexpected request %s", route)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	cftest.Target(t, server, fakeAccessToken, fakeRefreshToken)
+
+	client, err := cloudfoundry.New(hclog.NewNullLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestWaitProcessAwaitsReadiness(t *testing.T) {
+	// The instances and their readiness are fetched once per poll: the instance is routable from the
+	// second poll on
+	client := readinessCloudController(t, 2)
+
+	p := Platform{
+		config: Config{ReadinessCheck: &ReadinessCheckConfig{Type: "port"}, deploymentTimeout: time.Minute},
+		log:    hclog.NewNullLogger(),
+	}
+	start := time.Now()
+	err := p.waitProcess(&DeploymentState{client: client, deployment: &Deployment{AppGUID: "app-guid"}})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestWaitProcessTimesOutWhenNotRoutable(t *testing.T) {
+	client := readinessCloudController(t, 1000)
+
+	p := Platform{
+		config: Config{ReadinessCheck: &ReadinessCheckConfig{Type: "port"}, deploymentTimeout: 500 * time.Millisecond},
+		log:    hclog.NewNullLogger(),
+	}
+	err := p.waitProcess(&DeploymentState{client: client, deployment: &Deployment{AppGUID: "app-guid"}})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "timeout")
+		assert.Contains(t, err.Error(), "web=[RUNNING]")
+	}
+}
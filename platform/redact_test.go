@@ -0,0 +1,248 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/hashicorp/waypoint/builtin/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry/cftest"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+const (
+	fakeAccessToken    = "fake-access-token-1234"
+	fakeRefreshToken   = "fake-refresh-token-1234"
+	fakeDockerPassword = "fake-docker-password"
+	fakeEnvSecret      = "fake-env-secret"
+	fakeFileSecret     = "fake-file-secret"
+)
+
+var fakeSecrets = []string{fakeAccessToken, fakeRefreshToken, fakeDockerPassword, fakeEnvSecret, fakeFileSecret}
+
+// fakeCloudController serves the Cloud Controller and UAA requests of a deployment. It leaks the tokens
+// and the secrets it received in warnings, process commands, audit events and errors, as a verbose API would.
+// record is called with the route and body of each request, if not nil.
+func fakeCloudController(t *testing.T, failEnv bool, record func(route string, body []byte)) *httptest.Server {
+	var mu sync.Mutex
+	leaked := []string{fakeAccessToken, fakeRefreshToken}
+	list := cftest.List
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		for _, secret := range fakeSecrets {
+			if strings.Contains(string(body), secret) {
+				leaked = append(leaked, secret)
+			}
+		}
+		leak := strings.Join(leaked, " ")
+		if record != nil {
+			record(r.Method+" "+r.URL.Path, body)
+		}
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Cf-Warnings", url.QueryEscape("deprecated: "+leak))
+
+		var response interface{}
+		status := http.StatusOK
+		switch route := r.Method + " " + r.URL.Path; route {
+		case "POST /oauth/token":
+			response = map[string]interface{}{
+				"access_token":  fakeAccessToken,
+				"refresh_token": fakeRefreshToken,
+				"token_type":    "bearer",
+			}
+		case "GET /v3/organizations":
+			response = list(map[string]interface{}{"guid": "org-guid", "name": "org"})
+		case "GET /v3/spaces":
+			response = list(map[string]interface{}{"guid": "space-guid", "name": "space"})
+		case "GET /v3/apps":
+			response = list()
+		case "POST /v3/apps":
+			response = map[string]interface{}{"guid": "app-guid", "name": "app", "state": "STARTED"}
+		case "DELETE /v3/apps/app-guid":
+			status = http.StatusAccepted
+		case "POST /v3/packages":
+			response = map[string]interface{}{"guid": "package-guid", "type": "docker", "state": "READY"}
+		case "PATCH /v3/apps/app-guid/environment_variables":
+			if failEnv {
+				status = http.StatusUnprocessableEntity
+				response = map[string]interface{}{"errors": []interface{}{map[string]interface{}{
+					"code":   10008,
+					"title":  "CF-UnprocessableEntity",
+					"detail": "invalid environment variables: " + leak,
+				}}}
+			} else {
+				response = map[string]interface{}{"var": map[string]interface{}{}}
+			}
+		case "GET /v3/audit_events":
+			response = list(map[string]interface{}{
+				"guid":       "event-guid",
+				"type":       "audit.app.update",
+				"created_at": "2021-01-01T00:00:00Z",
+				"data":       map[string]interface{}{"request": map[string]interface{}{"command": leak}},
+			})
+		case "POST /v3/builds":
+			response = map[string]interface{}{
+				"guid":    "build-guid",
+				"state":   "STAGED",
+				"droplet": map[string]interface{}{"guid": "droplet-guid"},
+			}
+		case "POST /v3/deployments":
+			response = map[string]interface{}{"guid": "deployment-guid"}
+		case "POST /v3/spaces/space-guid/actions/apply_manifest":
+			w.Header().Set("Location", "http://"+r.Host+"/v3/jobs/job-guid")
+			status = http.StatusAccepted
+		case "POST /v3/apps/app-guid/processes/web/actions/scale":
+			response = map[string]interface{}{"guid": "process-guid", "type": "web"}
+		case "GET /v3/jobs/job-guid":
+			response = map[string]interface{}{"guid": "job-guid", "state": "COMPLETE"}
+		case "PATCH /v3/processes/process-guid":
+			response = map[string]interface{}{"guid": "process-guid", "type": "web"}
+		case "GET /v3/apps/app-guid/processes":
+			response = list(
+				map[string]interface{}{"guid": "process-guid", "type": "web", "command": "run " + leak, "instances": 1},
+				map[string]interface{}{"guid": "worker-guid", "type": "worker", "command": "work " + leak, "instances": 1},
+			)
+		case "GET /v3/processes/process-guid/stats":
+			response = list(map[string]interface{}{"type": "web", "index": 0, "state": "RUNNING", "uptime": 1})
+		case "GET /v3/processes/worker-guid/stats":
+			response = list(map[string]interface{}{"type": "worker", "index": 0, "state": "RUNNING", "uptime": 1})
+		case "GET /v3/domains":
+			response = list(map[string]interface{}{"guid": "domain-guid", "name": "example.com"})
+		case "POST /v3/routes":
+			response = map[string]interface{}{"guid": "route-guid", "host": "app", "url": "app.example.com"}
+		case "POST /v3/routes/route-guid/destinations":
+			response = map[string]interface{}{}
+		default:
+			t.Errorf("unexpected request %s", route)
+			status = http.StatusNotFound
+		}
+
+		w.WriteHeader(status)
+		if response != nil {
+			_ = json.NewEncoder(w).Encode(response)
+		}
+	}))
+}
+
+func TestDeployRedactsSecrets(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		failEnv bool
+	}{
+		{name: "success"},
+		{name: "failure", failEnv: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := fakeCloudController(t, tc.failEnv, nil)
+			defer server.Close()
+			cftest.Target(t, server, fakeAccessToken, fakeRefreshToken)
+			t.Setenv("CF_DOCKER_PASSWORD", fakeDockerPassword)
+
+			srcDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(srcDir, ".env"), []byte("FILE_SECRET="+fakeFileSecret+"\n"), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var uiOutput bytes.Buffer
+			output := color.Output
+			color.Output = &uiOutput
+			defer func() { color.Output = output }()
+
+			var logOutput bytes.Buffer
+			log := hclog.New(&hclog.LoggerOptions{Output: &logOutput, Level: hclog.Trace})
+
+			p := Platform{config: Config{
+				Organisation:             "org",
+				Space:                    "space",
+				Domain:                   "example.com",
+				DeploymentTimeoutSeconds: "10s",
+				Docker:                   &DockerConfig{Username: "user"},
+				Env:                      map[string]string{"SECRET": fakeEnvSecret},
+				EnvFromFile:              []string{".env"},
+			}}
+
+			_, err = p.Deploy(
+				context.Background(),
+				log,
+				&component.Source{App: "app", Path: srcDir},
+				&docker.Image{Image: "registry.example.com/app", Tag: "latest"},
+				&component.DeploymentConfig{},
+				terminal.NonInteractiveUI(context.Background()),
+			)
+
+			var errOutput string
+			if tc.failEnv {
+				assert.Error(t, err)
+				errOutput = err.Error()
+			} else {
+				assert.NoError(t, err)
+			}
+
+			captured := fmt.Sprintf("%s\n%s\n%s", logOutput.String(), uiOutput.String(), errOutput)
+			assert.Contains(t, captured, "deprecated: ********")
+			for _, secret := range fakeSecrets {
+				assert.NotContains(t, captured, secret)
+			}
+		})
+	}
+}
+
+func TestDeployKeepsPlainEnvValues(t *testing.T) {
+	server := fakeCloudController(t, false, nil)
+	defer server.Close()
+	cftest.Target(t, server, fakeAccessToken, fakeRefreshToken)
+	t.Setenv(dockerConfigEnv, t.TempDir())
+
+	var uiOutput bytes.Buffer
+	output := color.Output
+	color.Output = &uiOutput
+	defer func() { color.Output = output }()
+
+	p := Platform{config: Config{
+		Organisation: "org",
+		Space:        "space",
+		Domain:       "example.com",
+		Env: map[string]string{
+			"APP_DOMAIN": "example.com",
+			"DB_URL":     "postgres://db.example.com/app",
+			"SECRET":     fakeEnvSecret,
+			"PIN":        "4711",
+		},
+		SensitiveEnv: []string{"DB_URL", "PIN"},
+	}}
+
+	_, err := p.Deploy(
+		context.Background(),
+		hclog.NewNullLogger(),
+		&component.Source{App: "app"},
+		&docker.Image{Image: "registry.example.com/app", Tag: "latest"},
+		&component.DeploymentConfig{},
+		terminal.NonInteractiveUI(context.Background()),
+	)
+	assert.NoError(t, err)
+
+	assert.Equal(t, utils.RedactedValue, p.redactor.Redact("postgres://db.example.com/app"))
+	assert.Equal(t, utils.RedactedValue, p.redactor.Redact("4711"))
+	assert.Contains(t, uiOutput.String(), "app.example.com")
+	assert.NotContains(t, uiOutput.String(), fakeEnvSecret)
+}
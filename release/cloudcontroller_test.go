@@ -0,0 +1,317 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry/cftest"
+)
+
+// fakeRoute is a route of the fake Cloud Controller, mapped to the apps of its destinations
+type fakeRoute struct {
+	guid   string
+	host   string
+	domain string
+	path   string
+	port   int
+	apps   []string
+}
+
+func (r *fakeRoute) url() string {
+	if r.port != 0 {
+		return fmt.Sprintf("%s:%d", r.domain, r.port)
+	}
+	if r.host == "" {
+		return r.domain + r.path
+	}
+	return fmt.Sprintf("%s.%s%s", r.host, r.domain, r.path)
+}
+
+func (r *fakeRoute) resource() map[string]interface{} {
+	protocol := "http"
+	if r.port != 0 {
+		protocol = "tcp"
+	}
+	destinations := []interface{}{}
+	for _, app := range r.apps {
+		destinations = append(destinations, map[string]interface{}{
+			"guid": r.guid + "-" + app,
+			"app":  map[string]interface{}{"guid": app},
+		})
+	}
+	return map[string]interface{}{
+		"guid":         r.guid,
+		"host":         r.host,
+		"path":         r.path,
+		"port":         r.port,
+		"protocol":     protocol,
+		"url":          r.url(),
+		"destinations": destinations,
+		"relationships": map[string]interface{}{
+			"domain": map[string]interface{}{"data": map[string]interface{}{"guid": "domain-" + r.domain}},
+		},
+	}
+}
+
+// fakeRouteBinding binds a route service to a route of the fake Cloud Controller
+type fakeRouteBinding struct {
+	guid            string
+	route           string
+	serviceInstance string
+}
+
+func (b *fakeRouteBinding) resource() map[string]interface{} {
+	return map[string]interface{}{
+		"guid": b.guid,
+		"relationships": map[string]interface{}{
+			"route":            map[string]interface{}{"data": map[string]interface{}{"guid": b.route}},
+			"service_instance": map[string]interface{}{"data": map[string]interface{}{"guid": b.serviceInstance}},
+		},
+	}
+}
+
+// fakeCloudController serves the Cloud Controller and UAA requests of releases, keeping the routes of
+// the space in memory. Apps are named like their GUID, and have a single web instance unless set in
+// instances. Domains exist for any name, those starting with tcp. are TCP domains. Service instances
+// exist for any name, with the GUID service-<name>.
+type fakeCloudController struct {
+	t        *testing.T
+	mu       sync.Mutex
+	routes   []*fakeRoute
+	bindings []*fakeRouteBinding
+	// instances lists the instance states of the web process of apps, e.g. CRASHED
+	instances map[string][]string
+	// requests lists the requests changing routes and bindings, e.g. POST /v3/routes
+	requests []string
+}
+
+func newFakeCloudController(t *testing.T, routes ...*fakeRoute) *fakeCloudController {
+	cc := &fakeCloudController{t: t, routes: routes, instances: map[string][]string{}}
+	server := httptest.NewServer(http.HandlerFunc(cc.serve))
+	t.Cleanup(server.Close)
+	cftest.Target(t, server, "access-token", "refresh-token")
+	return cc
+}
+
+// filtered tells whether the values of the query filter key exclude value
+func filtered(r *http.Request, key string, value string) bool {
+	values, ok := r.URL.Query()[key]
+	if !ok {
+		return false
+	}
+	for _, v := range strings.Split(strings.Join(values, ","), ",") {
+		if v == value {
+			return false
+		}
+	}
+	return true
+}
+
+func (cc *fakeCloudController) serve(w http.ResponseWriter, r *http.Request) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	match := func(method string, pattern string) bool {
+		ok, _ := path.Match(pattern, r.URL.Path)
+		return r.Method == method && ok
+	}
+	segments := strings.Split(r.URL.Path, "/")
+	if r.Method != http.MethodGet && !match(http.MethodPost, "/oauth/token") {
+		cc.requests = append(cc.requests, r.Method+" "+r.URL.Path)
+	}
+
+	var response interface{}
+	status := http.StatusOK
+	switch {
+	case match(http.MethodPost, "/oauth/token"):
+		response = map[string]interface{}{
+			"access_token":  "access-token",
+			"refresh_token": "refresh-token",
+			"token_type":    "bearer",
+		}
+	case match(http.MethodGet, "/v3/domains"):
+		var found []interface{}
+		for _, name := range strings.Split(r.URL.Query().Get("names"), ",") {
+			domain := map[string]interface{}{"guid": "domain-" + name, "name": name}
+			if strings.HasPrefix(name, "tcp.") {
+				domain["router_group"] = map[string]interface{}{"guid": "router-group"}
+			}
+			found = append(found, domain)
+		}
+		response = cftest.List(found...)
+	case match(http.MethodGet, "/v3/routes"):
+		var found []interface{}
+		for _, route := range cc.routes {
+			if !filtered(r, "guids", route.guid) &&
+				!filtered(r, "domain_guids", "domain-"+route.domain) &&
+				!filtered(r, "hosts", route.host) &&
+				!filtered(r, "paths", route.path) &&
+				!filtered(r, "ports", strconv.Itoa(route.port)) {
+				found = append(found, route.resource())
+			}
+		}
+		response = cftest.List(found...)
+	case match(http.MethodPost, "/v3/routes"):
+		var body struct {
+			Host          string `json:"host"`
+			Path          string `json:"path"`
+			Port          int    `json:"port"`
+			Relationships struct {
+				Domain struct {
+					Data struct {
+						GUID string `json:"guid"`
+					} `json:"data"`
+				} `json:"domain"`
+			} `json:"relationships"`
+		}
+		cc.decode(r, &body)
+		route := &fakeRoute{
+			guid:   fmt.Sprintf("created-route-%d", len(cc.routes)+1),
+			host:   body.Host,
+			domain: strings.TrimPrefix(body.Relationships.Domain.Data.GUID, "domain-"),
+			path:   body.Path,
+			port:   body.Port,
+		}
+		cc.routes = append(cc.routes, route)
+		response = route.resource()
+		status = http.StatusCreated
+	case match(http.MethodPatch, "/v3/routes/*"):
+		response = cc.route(segments[3]).resource()
+	case match(http.MethodPost, "/v3/routes/*/destinations"):
+		var body struct {
+			Destinations []struct {
+				App struct {
+					GUID string `json:"guid"`
+				} `json:"app"`
+			} `json:"destinations"`
+		}
+		cc.decode(r, &body)
+		route := cc.route(segments[3])
+		for _, destination := range body.Destinations {
+			if !contains(route.apps, destination.App.GUID) {
+				route.apps = append(route.apps, destination.App.GUID)
+			}
+		}
+		response = map[string]interface{}{"destinations": route.resource()["destinations"]}
+	case match(http.MethodDelete, "/v3/routes/*/destinations/*"):
+		route := cc.route(segments[3])
+		var apps []string
+		for _, app := range route.apps {
+			if route.guid+"-"+app != segments[5] {
+				apps = append(apps, app)
+			}
+		}
+		route.apps = apps
+		status = http.StatusNoContent
+	case match(http.MethodGet, "/v3/apps"):
+		names := r.URL.Query().Get("names")
+		if names == "" {
+			names = r.URL.Query().Get("guids")
+		}
+		var found []interface{}
+		for _, guid := range strings.Split(names, ",") {
+			found = append(found, map[string]interface{}{"guid": guid, "name": guid, "state": "STARTED"})
+		}
+		response = cftest.List(found...)
+	case match(http.MethodGet, "/v3/apps/*/processes"):
+		response = cftest.List(map[string]interface{}{"guid": segments[3] + "-web", "type": "web"})
+	case match(http.MethodGet, "/v3/processes/*/stats"):
+		states, ok := cc.instances[strings.TrimSuffix(segments[3], "-web")]
+		if !ok {
+			states = []string{"RUNNING"}
+		}
+		var stats []interface{}
+		for i, state := range states {
+			stats = append(stats, map[string]interface{}{"type": "web", "index": i, "state": state, "uptime": 60})
+		}
+		response = cftest.List(stats...)
+	case match(http.MethodGet, "/v3/service_instances"):
+		name := r.URL.Query().Get("names")
+		response = cftest.List(map[string]interface{}{"guid": "service-" + name, "name": name, "type": "user-provided"})
+	case match(http.MethodGet, "/v3/service_route_bindings"):
+		var found []interface{}
+		for _, binding := range cc.bindings {
+			if !filtered(r, "route_guids", binding.route) &&
+				!filtered(r, "service_instance_guids", binding.serviceInstance) {
+				found = append(found, binding.resource())
+			}
+		}
+		response = cftest.List(found...)
+	case match(http.MethodPost, "/v3/service_route_bindings"):
+		var body struct {
+			Relationships struct {
+				Route struct {
+					Data struct {
+						GUID string `json:"guid"`
+					} `json:"data"`
+				} `json:"route"`
+				ServiceInstance struct {
+					Data struct {
+						GUID string `json:"guid"`
+					} `json:"data"`
+				} `json:"service_instance"`
+			} `json:"relationships"`
+		}
+		cc.decode(r, &body)
+		binding := &fakeRouteBinding{
+			guid:            fmt.Sprintf("binding-%d", len(cc.bindings)+1),
+			route:           body.Relationships.Route.Data.GUID,
+			serviceInstance: body.Relationships.ServiceInstance.Data.GUID,
+		}
+		cc.bindings = append(cc.bindings, binding)
+		// Bindings of user-provided services are created synchronously, without a job
+		response = binding.resource()
+		status = http.StatusCreated
+	case match(http.MethodDelete, "/v3/service_route_bindings/*"):
+		var bindings []*fakeRouteBinding
+		for _, binding := range cc.bindings {
+			if binding.guid != segments[3] {
+				bindings = append(bindings, binding)
+			}
+		}
+		cc.bindings = bindings
+		status = http.StatusNoContent
+	default:
+		cc.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		status = http.StatusNotFound
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if response != nil {
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+// route returns the route with the given GUID, failing the test if there is none
+func (cc *fakeCloudController) route(guid string) *fakeRoute {
+	for _, route := range cc.routes {
+		if route.guid == guid {
+			return route
+		}
+	}
+	cc.t.Errorf("route %s not found", guid)
+	return &fakeRoute{guid: guid}
+}
+
+func (cc *fakeCloudController) decode(r *http.Request, body interface{}) {
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		cc.t.Errorf("invalid body of %s %s: %v", r.Method, r.URL, err)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
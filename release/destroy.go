@@ -2,8 +2,12 @@ package release
 
 import (
 	"context"
+	"fmt"
 
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccerror"
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/cloudfoundry"
 )
 
 // DestroyFunc implements the Destroyer interface
@@ -38,6 +42,60 @@ func (r *Releaser) DestroyFunc() interface{} {
 //
 // If an error is returned, Waypoint stops the execution flow and
 // returns an error to the user.
-func (r *Releaser) destroy(ctx context.Context, ui terminal.UI, release *Release) error {
+func (r *Releaser) destroy(ctx context.Context, log hclog.Logger, ui terminal.UI, release *Release) error {
+	if release.RouteServiceInstanceGuid == "" {
+		return nil
+	}
+
+	sg := ui.StepGroup()
+	step := sg.Add("Connecting to Cloud Foundry")
+
+	client, err := cloudfoundry.New(log)
+	if err != nil {
+		step.Abort()
+		return fmt.Errorf("unable to create Cloud Foundry client: %v", err)
+	}
+
+	step.Update(fmt.Sprintf("Connecting to Cloud Foundry at %s", client.CloudControllerURL()))
+	step.Done()
+
+	step = sg.Add("Unbinding route service")
+	route, err := client.GetRoute(release.RouteGuid)
+	if _, ok := err.(ccerror.ResourceNotFoundError); ok {
+		// Deleting the route deleted its route service bindings too
+		step.Update("Route was deleted, nothing to unbind")
+		step.Done()
+		return nil
+	}
+	if err != nil {
+		step.Abort()
+		return fmt.Errorf("unable to get route %v: %v", release.RouteGuid, err)
+	}
+
+	// The route is shared by all releases: keep the route service while a newer
+	// release is using the route
+	for _, destination := range route.Destinations {
+		if destination.App.GUID != release.AppGuid {
+			step.Update("Route is used by another release, keeping the route service")
+			step.Done()
+			return nil
+		}
+	}
+
+	bindings, err := client.GetRouteBindings(release.RouteGuid, release.RouteServiceInstanceGuid)
+	if err != nil {
+		step.Abort()
+		return fmt.Errorf("unable to get route bindings: %v", err)
+	}
+
+	for _, binding := range bindings {
+		err = client.DeleteRouteBinding(binding.GUID)
+		if err != nil {
+			step.Abort()
+			return fmt.Errorf("unable to unbind route service: %v", err)
+		}
+	}
+	step.Done()
+
 	return nil
 }
@@ -25,9 +25,12 @@ type Release struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Url              string `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
-	RouteGuid        string `protobuf:"bytes,2,opt,name=route_guid,json=routeGuid,proto3" json:"route_guid,omitempty"`
-	StopOldInstances bool   `protobuf:"varint,3,opt,name=stop_old_instances,json=stopOldInstances,proto3" json:"stop_old_instances,omitempty"`
+	Url                      string   `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	RouteGuid                string   `protobuf:"bytes,2,opt,name=route_guid,json=routeGuid,proto3" json:"route_guid,omitempty"`
+	StopOldInstances         bool     `protobuf:"varint,3,opt,name=stop_old_instances,json=stopOldInstances,proto3" json:"stop_old_instances,omitempty"`
+	RouteGuids               []string `protobuf:"bytes,4,rep,name=route_guids,json=routeGuids,proto3" json:"route_guids,omitempty"`
+	RouteServiceInstanceGuid string   `protobuf:"bytes,5,opt,name=route_service_instance_guid,json=routeServiceInstanceGuid,proto3" json:"route_service_instance_guid,omitempty"`
+	AppGuid                  string   `protobuf:"bytes,6,opt,name=app_guid,json=appGuid,proto3" json:"app_guid,omitempty"`
 }
 
 func (x *Release) Reset() {
@@ -83,22 +86,51 @@ func (x *Release) GetStopOldInstances() bool {
 	return false
 }
 
+func (x *Release) GetRouteGuids() []string {
+	if x != nil {
+		return x.RouteGuids
+	}
+	return nil
+}
+
+func (x *Release) GetRouteServiceInstanceGuid() string {
+	if x != nil {
+		return x.RouteServiceInstanceGuid
+	}
+	return ""
+}
+
+func (x *Release) GetAppGuid() string {
+	if x != nil {
+		return x.AppGuid
+	}
+	return ""
+}
+
 var File_release_output_proto protoreflect.FileDescriptor
 
 var file_release_output_proto_rawDesc = []byte{
 	0x0a, 0x14, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2f, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74,
 	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x22,
-	0x68, 0x0a, 0x07, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72,
-	0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x1d, 0x0a, 0x0a,
-	0x72, 0x6f, 0x75, 0x74, 0x65, 0x5f, 0x67, 0x75, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x09, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x47, 0x75, 0x69, 0x64, 0x12, 0x2c, 0x0a, 0x12, 0x73,
-	0x74, 0x6f, 0x70, 0x5f, 0x6f, 0x6c, 0x64, 0x5f, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65,
-	0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x73, 0x74, 0x6f, 0x70, 0x4f, 0x6c, 0x64,
-	0x49, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x42, 0x3a, 0x5a, 0x38, 0x67, 0x69, 0x74,
-	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x77, 0x69, 0x73, 0x73, 0x63, 0x6f, 0x6d,
-	0x2f, 0x77, 0x61, 0x79, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x2d, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e,
-	0x2d, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x72, 0x79, 0x2f, 0x72, 0x65,
-	0x6c, 0x65, 0x61, 0x73, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0xe3, 0x01, 0x0a, 0x07, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x75,
+	0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x1d, 0x0a,
+	0x0a, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x5f, 0x67, 0x75, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x09, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x47, 0x75, 0x69, 0x64, 0x12, 0x2c, 0x0a, 0x12,
+	0x73, 0x74, 0x6f, 0x70, 0x5f, 0x6f, 0x6c, 0x64, 0x5f, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63,
+	0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x73, 0x74, 0x6f, 0x70, 0x4f, 0x6c,
+	0x64, 0x49, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x6f,
+	0x75, 0x74, 0x65, 0x5f, 0x67, 0x75, 0x69, 0x64, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x0a, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x47, 0x75, 0x69, 0x64, 0x73, 0x12, 0x3d, 0x0a, 0x1b, 0x72,
+	0x6f, 0x75, 0x74, 0x65, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x69, 0x6e, 0x73,
+	0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x67, 0x75, 0x69, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x18, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x49, 0x6e,
+	0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x47, 0x75, 0x69, 0x64, 0x12, 0x19, 0x0a, 0x08, 0x61, 0x70,
+	0x70, 0x5f, 0x67, 0x75, 0x69, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x70,
+	0x70, 0x47, 0x75, 0x69, 0x64, 0x42, 0x3a, 0x5a, 0x38, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x77, 0x69, 0x73, 0x73, 0x63, 0x6f, 0x6d, 0x2f, 0x77, 0x61, 0x79,
+	0x70, 0x6f, 0x69, 0x6e, 0x74, 0x2d, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2d, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x72, 0x79, 0x2f, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73,
+	0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -3,9 +3,13 @@ package release
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3/constant"
+	"code.cloudfoundry.org/cli/resources"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/waypoint-plugin-sdk/component"
 	proto "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
@@ -15,13 +19,55 @@ import (
 	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
 )
 
+// RouteConfig describes a route to be mapped to the released app
+type RouteConfig struct {
+	Host   string `hcl:"host,optional"`
+	Domain string `hcl:"domain"`
+	Path   string `hcl:"path,optional"`
+	// Port is required for routes on TCP domains
+	Port          int    `hcl:"port,optional"`
+	AppPort       int    `hcl:"app_port,optional"`
+	LoadBalancing string `hcl:"loadbalancing,optional"`
+	Protocol      string `hcl:"protocol,optional"`
+}
+
+// ProbeConfig describes an HTTP probe of the release URL, run by the status check
+type ProbeConfig struct {
+	Path string `hcl:"path,optional"`
+	// Scheme overrides the scheme of the release URL, e.g. to probe over https
+	Scheme             string `hcl:"scheme,optional"`
+	ExpectedStatus     []int  `hcl:"expected_status,optional"`
+	TimeoutSeconds     int    `hcl:"timeout,optional"`
+	InsecureSkipVerify bool   `hcl:"insecure_skip_verify,optional"`
+}
+
 type Config struct {
-	Domain           string   `hcl:"domain"`
-	Hostname         string   `hcl:"hostname,optional"`
-	AdditionalRoutes []string `hcl:"additional_routes,optional"`
-	StopOldInstances bool     `hcl:"stop_old_instances,optional"`
+	Domain           string         `hcl:"domain,optional"`
+	Hostname         string         `hcl:"hostname,optional"`
+	AdditionalRoutes []string       `hcl:"additional_routes,optional"`
+	Routes           []*RouteConfig `hcl:"route,block"`
+	StopOldInstances bool           `hcl:"stop_old_instances,optional"`
+	// InternalHostname is the host of the internal route on InternalDomain, reachable by other apps
+	// over the container-to-container network
+	InternalHostname string `hcl:"internal_hostname,optional"`
+	InternalDomain   string `hcl:"internal_domain,optional"`
+	// RouteService is the name of a service instance bound as route service to the release route
+	RouteService string `hcl:"route_service,optional"`
+	// Route options of the hostname and additional routes
+	LoadBalancing string `hcl:"loadbalancing,optional"`
+	Protocol      string `hcl:"protocol,optional"`
+	// Probe of the release URL, merged into the status report
+	Probe *ProbeConfig `hcl:"probe,block"`
+	// HealthPolicy aggregates the health of the route destinations, the probe is then merged with worst-of
+	HealthPolicy *platform.HealthPolicyConfig `hcl:"health_policy,block"`
 }
 
+// DefaultInternalDomain is the domain of the internal route, unless internal_domain is set
+const DefaultInternalDomain = "apps.internal"
+
+// maxConcurrentDestinations bounds the number of route destinations whose health is fetched at the same time
+const maxConcurrentDestinations = 4
+
 type Releaser struct {
 	config Config
 	log    hclog.Logger
@@ -32,6 +78,66 @@ func (r *Releaser) Config() (interface{}, error) {
 	return &r.config, nil
 }
 
+// ConfigSet implements ConfigurableNotify
+func (r *Releaser) ConfigSet(config interface{}) error {
+	c, ok := config.(*Config)
+	if !ok {
+		// The Waypoint SDK should ensure this never gets hit
+		return fmt.Errorf("expected *Config as parameter")
+	}
+
+	if c.Domain == "" && (c.Hostname != "" || len(c.AdditionalRoutes) > 0) {
+		return fmt.Errorf("domain is required when hostname or additional_routes are set")
+	}
+
+	if c.RouteService != "" && c.Hostname == "" && len(c.Routes) == 0 {
+		return fmt.Errorf("route_service requires a release route (hostname or route block)")
+	}
+
+	if err := utils.ValidateRouteOptions(c.LoadBalancing, c.Protocol); err != nil {
+		return fmt.Errorf("invalid route options: %v", err)
+	}
+
+	for _, route := range c.Routes {
+		if err := utils.ValidateRoutePath(route.Path); err != nil {
+			return fmt.Errorf("invalid path for route on domain %v: %v", route.Domain, err)
+		}
+		if err := utils.ValidateRouteOptions(route.LoadBalancing, route.Protocol); err != nil {
+			return fmt.Errorf("invalid options for route on domain %v: %v", route.Domain, err)
+		}
+	}
+
+	if c.Probe != nil {
+		if err := validateProbe(c.Probe); err != nil {
+			return fmt.Errorf("invalid probe: %v", err)
+		}
+	}
+
+	if _, err := c.HealthPolicy.Policy(); err != nil {
+		return fmt.Errorf("invalid health_policy: %v", err)
+	}
+
+	return nil
+}
+
+func validateProbe(probe *ProbeConfig) error {
+	if err := utils.ValidateRoutePath(probe.Path); err != nil {
+		return fmt.Errorf("invalid path: %v", err)
+	}
+	if probe.Scheme != "" && probe.Scheme != "http" && probe.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https, got %q", probe.Scheme)
+	}
+	for _, status := range probe.ExpectedStatus {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("expected_status %d is not a valid HTTP status code", status)
+		}
+	}
+	if probe.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+	return nil
+}
+
 // ReleaseFunc Implement Builder
 func (r *Releaser) ReleaseFunc() interface{} {
 	// return a function which will be called by Waypoint
@@ -58,11 +164,7 @@ func (r *Releaser) Release(
 	deployment *platform.Deployment,
 ) (*Release, error) {
 	var release Release
-	var hostname string
-
-	if r.config.Hostname != "" {
-		hostname = r.config.Hostname
-	}
+	release.AppGuid = deployment.AppGUID
 
 	sg := ui.StepGroup()
 	step := sg.Add("Connecting to Cloud Foundry")
@@ -92,84 +194,71 @@ func (r *Releaser) Release(
 	}
 	step.Done()
 
-	if r.config.Hostname == "" {
-		r.config.Hostname = src.App
+	// The first route (the hostname on the default domain, or the first route block)
+	// is the one used as the release URL
+	var routes []*RouteConfig
+	if r.config.Hostname != "" {
+		routes = append(routes, &RouteConfig{
+			Host:          r.config.Hostname,
+			Domain:        r.config.Domain,
+			LoadBalancing: r.config.LoadBalancing,
+			Protocol:      r.config.Protocol,
+		})
 	}
+	routes = append(routes, r.config.Routes...)
 
-	routeUrl := fmt.Sprintf("%v.%v", r.config.Hostname, r.config.Domain)
-	step = sg.Add(fmt.Sprintf("Binding route %v to deployment", routeUrl))
-	domains, err := client.GetDomainsByName(r.config.Domain)
-	if err != nil || len(domains) == 0 {
-		step.Abort()
-		return nil, fmt.Errorf("failed to get specified domain: %v", err)
+	domains := map[string]resources.Domain{}
+	for i, routeConfig := range routes {
+		route, err := r.releaseRoute(client, sg, domains, routeConfig, deployment)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			release.Url = fmt.Sprintf("%v://%v", route.Protocol, route.URL)
+			release.RouteGuid = route.GUID
+		}
+		release.RouteGuids = append(release.RouteGuids, route.GUID)
 	}
-	domain := domains[0]
 
-	// Map original route, if not empty
-	if hostname != "" {
-		route, err := client.UpsertRoute(hostname, domain, deployment.SpaceGUID)
+	if r.config.RouteService != "" {
+		guid, err := r.bindRouteService(client, sg, release.RouteGuid, deployment)
 		if err != nil {
-			step.Abort()
-			return nil, fmt.Errorf("failed to get or create route: %v", err)
+			return nil, err
 		}
+		release.RouteServiceInstanceGuid = guid
+	}
 
-		// Map route
-		err = client.MapRoute(route.GUID, deployment.AppGUID)
-		if err != nil {
-			step.Abort()
-			return nil, fmt.Errorf("failed to map route: %v", err)
+	// The internal route is released like the other routes, so that other apps only
+	// reach the released deployment
+	if r.config.InternalHostname != "" {
+		internalDomain := r.config.InternalDomain
+		if internalDomain == "" {
+			internalDomain = DefaultInternalDomain
 		}
-		step.Done()
-		release.Url = fmt.Sprintf("%v://%v", route.Protocol, route.URL)
-		release.RouteGuid = route.GUID
-
-		step = sg.Add("unmapping other applications")
-		// Unmap all others applications
-		for _, destination := range route.Destinations {
-			step.Update(fmt.Sprintf("unmapping %v", destination.App.GUID))
-			err = client.UnmapRoute(route.GUID, destination.GUID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to unmap route from destination app with GUID %v", destination.App.GUID)
-			}
+		route, err := r.releaseRoute(client, sg, domains, &RouteConfig{
+			Host:   r.config.InternalHostname,
+			Domain: internalDomain,
+		}, deployment)
+		if err != nil {
+			return nil, err
 		}
-		step.Done()
+		release.RouteGuids = append(release.RouteGuids, route.GUID)
 	}
 
 	step = sg.Add("mapping additional routes (if available)")
 	for _, additionalRoute := range r.config.AdditionalRoutes {
-		route, err := client.UpsertRoute(additionalRoute, domain, deployment.SpaceGUID)
+		route, err := r.releaseRoute(client, sg, domains, &RouteConfig{
+			Host:          additionalRoute,
+			Domain:        r.config.Domain,
+			LoadBalancing: r.config.LoadBalancing,
+			Protocol:      r.config.Protocol,
+		}, deployment)
 		if err != nil {
 			step.Abort()
-			return nil, fmt.Errorf("failed to get or create route: %v", err)
-		}
-
-		step.Update(fmt.Sprintf("mapping %v", route))
-		err = client.MapRoute(route.GUID, deployment.AppGUID)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"unable to map route %v to app %v: %v",
-				route,
-				deployment.AppGUID,
-				err,
-			)
+			return nil, err
 		}
-
-		step = sg.Add(fmt.Sprintf("unmapping previous app from %v", route))
-
-		// Unmap other routes associated with this one
-		for _, dest := range route.Destinations {
-			step.Update("unmapping %v", dest.App.GUID)
-			err = client.UnmapRoute(route.GUID, dest.GUID)
-			if err != nil {
-				return nil, fmt.Errorf(
-					"unable to unmap route %v from app %v: %v",
-					route,
-					dest.App.GUID,
-					err,
-				)
-			}
-		}
-		step.Done()
+		release.RouteGuids = append(release.RouteGuids, route.GUID)
 	}
 	step.Done()
 
@@ -204,6 +293,143 @@ func (r *Releaser) Release(
 	return &release, nil
 }
 
+// releaseRoute maps the given route to the deployed app and unmaps all the other
+// apps currently mapped to it. The route is created if it doesn't exist yet.
+func (r *Releaser) releaseRoute(
+	client *cloudfoundry.Client,
+	sg terminal.StepGroup,
+	domains map[string]resources.Domain,
+	routeConfig *RouteConfig,
+	deployment *platform.Deployment,
+) (*resources.Route, error) {
+	routeUrl := utils.RouteURL(routeConfig.Host, routeConfig.Domain, routeConfig.Path, routeConfig.Port)
+	step := sg.Add(fmt.Sprintf("Binding route %v to deployment", routeUrl))
+
+	domain, ok := domains[routeConfig.Domain]
+	if !ok {
+		found, err := client.GetDomainsByName(routeConfig.Domain)
+		if err != nil || len(found) == 0 {
+			step.Abort()
+			return nil, fmt.Errorf("failed to get domain %v: %v", routeConfig.Domain, err)
+		}
+		domain = found[0]
+		domains[routeConfig.Domain] = domain
+	}
+
+	if cloudfoundry.IsTCPDomain(domain) {
+		// Without a fixed port, every release would get a new random port
+		if routeConfig.Port == 0 || routeConfig.Host != "" || routeConfig.Path != "" {
+			step.Abort()
+			return nil, fmt.Errorf("route on TCP domain %v requires a port and no host or path", domain.Name)
+		}
+		if routeConfig.LoadBalancing != "" || routeConfig.Protocol != "" {
+			step.Abort()
+			return nil, fmt.Errorf("loadbalancing and protocol cannot be used with TCP domain %v", domain.Name)
+		}
+	} else if routeConfig.Port != 0 {
+		step.Abort()
+		return nil, fmt.Errorf("port can only be used with TCP domains, %v is an HTTP domain", domain.Name)
+	}
+
+	route, err := client.UpsertRoute(resources.Route{
+		DomainGUID: domain.GUID,
+		SpaceGUID:  deployment.SpaceGUID,
+		Host:       routeConfig.Host,
+		Path:       routeConfig.Path,
+		Port:       routeConfig.Port,
+	})
+	if err != nil {
+		step.Abort()
+		return nil, fmt.Errorf("failed to get or create route %v: %v", routeUrl, err)
+	}
+
+	if routeConfig.LoadBalancing != "" {
+		err = client.UpdateRouteOptions(route.GUID, cloudfoundry.RouteOptions{
+			LoadBalancing: routeConfig.LoadBalancing,
+		})
+		if err != nil {
+			step.Abort()
+			return nil, fmt.Errorf("failed to set options of route %v: %v", routeUrl, err)
+		}
+	}
+
+	// Unless set for the route, use the same app port as the deployment route
+	appPort := routeConfig.AppPort
+	if appPort == 0 {
+		appPort = int(deployment.AppPort)
+	}
+
+	err = client.MapRouteDestination(route.GUID, cloudfoundry.RouteDestination{
+		AppGUID:  deployment.AppGUID,
+		Port:     appPort,
+		Protocol: routeConfig.Protocol,
+	})
+	if err != nil {
+		step.Abort()
+		return nil, fmt.Errorf("failed to map route %v: %v", routeUrl, err)
+	}
+	step.Done()
+
+	step = sg.Add(fmt.Sprintf("unmapping previous apps from %v", routeUrl))
+	for _, destination := range route.Destinations {
+		// The route might already be mapped to this deployment, if it's being released again
+		if destination.App.GUID == deployment.AppGUID {
+			continue
+		}
+
+		step.Update(fmt.Sprintf("unmapping %v", destination.App.GUID))
+		err = client.UnmapRoute(route.GUID, destination.GUID)
+		if err != nil {
+			step.Abort()
+			return nil, fmt.Errorf(
+				"unable to unmap route %v from app %v: %v",
+				routeUrl,
+				destination.App.GUID,
+				err,
+			)
+		}
+	}
+	step.Done()
+
+	return &route, nil
+}
+
+// bindRouteService binds the configured route service to the route, unless it's already
+// bound from a previous release, and returns the GUID of the service instance
+func (r *Releaser) bindRouteService(
+	client *cloudfoundry.Client,
+	sg terminal.StepGroup,
+	routeGuid string,
+	deployment *platform.Deployment,
+) (string, error) {
+	step := sg.Add(fmt.Sprintf("Binding route service %v", r.config.RouteService))
+	serviceInstance, err := client.GetServiceInstances(deployment.SpaceGUID, r.config.RouteService)
+	if err != nil {
+		step.Abort()
+		return "", fmt.Errorf("unable to get route service %v: %v", r.config.RouteService, err)
+	}
+
+	bindings, err := client.GetRouteBindings(routeGuid, serviceInstance.GUID)
+	if err != nil {
+		step.Abort()
+		return "", fmt.Errorf("unable to get route bindings: %v", err)
+	}
+
+	if len(bindings) > 0 {
+		step.Update(fmt.Sprintf("Route service %v is already bound", r.config.RouteService))
+		step.Done()
+		return serviceInstance.GUID, nil
+	}
+
+	err = client.CreateRouteBinding(routeGuid, serviceInstance.GUID)
+	if err != nil {
+		step.Abort()
+		return "", fmt.Errorf("unable to bind route service %v: %v", r.config.RouteService, err)
+	}
+	step.Done()
+	return serviceInstance.GUID, nil
+}
+
 func (r *Releaser) listWarnings(warn ccv3.Warnings) {
 	if len(warn) > 0 {
 		for _, w := range warn {
@@ -274,22 +500,67 @@ func (r *Releaser) Status(
 		return &result, nil
 	}
 
-	var healthReports []*proto.StatusReport
+	policy, err := r.config.HealthPolicy.Policy()
+	if err != nil {
+		return nil, fmt.Errorf("invalid health_policy: %v", err)
+	}
 
-	for _, dest := range destinations {
-		healthStatus, err := state.client.GetHealthByGUID(dest.App.GUID)
+	healthReports := make([]*proto.StatusReport, len(destinations))
+	err = utils.ForEachConcurrently(len(destinations), maxConcurrentDestinations, func(i int) error {
+		healthStatus, err := state.client.GetHealthByGUID(destinations[i].App.GUID, policy)
 		if err != nil {
-			return nil, fmt.Errorf(
+			return fmt.Errorf(
 				"unable to get health for app %v: %v",
-				dest.App.GUID,
+				destinations[i].App.GUID,
 				err,
 			)
 		}
-		healthReports = append(healthReports, healthStatus)
+		healthReports[i] = healthStatus
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := utils.HealthSummary(healthReports...)
+	if policy != nil {
+		summary = utils.HealthSummaryWithPolicy(policy, healthReports...)
+	}
+
+	// The probe isn't outvoted by the destinations: a release URL returning errors is down,
+	// whatever the health of the apps behind it
+	if r.config.Probe != nil {
+		if probeReport := r.probe(ctx, release.Url); probeReport != nil {
+			summary = utils.HealthSummary(summary, probeReport)
+		}
 	}
 
 	step.Done()
-	return utils.HealthSummary(healthReports...), nil
+	return summary, nil
+}
+
+// probe checks the release URL over HTTP, returning nil if it can't be probed
+func (r *Releaser) probe(ctx context.Context, releaseUrl string) *proto.StatusReport {
+	u, err := url.Parse(releaseUrl)
+	if err != nil {
+		r.log.Warn("unable to parse release URL, skipping probe", "url", releaseUrl, "error", err)
+		return nil
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		r.log.Warn("release URL is not an HTTP URL, skipping probe", "url", releaseUrl)
+		return nil
+	}
+
+	if r.config.Probe.Scheme != "" {
+		u.Scheme = r.config.Probe.Scheme
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + r.config.Probe.Path
+
+	return utils.ProbeURL(ctx, u.String(), utils.ProbeOptions{
+		ExpectedStatus:     r.config.Probe.ExpectedStatus,
+		Timeout:            time.Duration(r.config.Probe.TimeoutSeconds) * time.Second,
+		InsecureSkipVerify: r.config.Probe.InsecureSkipVerify,
+	})
 }
 
 func (r *Release) URL() string { return r.Url }
@@ -297,3 +568,4 @@ func (r *Release) URL() string { return r.Url }
 var _ component.Release = (*Release)(nil)
 var _ component.ReleaseManager = (*Releaser)(nil)
 var _ component.Status = (*Releaser)(nil)
+var _ component.ConfigurableNotify = (*Releaser)(nil)
@@ -0,0 +1,94 @@
+package release
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleaseBindsRouteService(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		bindings []*fakeRouteBinding
+		created  int
+	}{
+		{name: "unbound route", created: 1},
+		{
+			name:     "route bound by a previous release",
+			bindings: []*fakeRouteBinding{{guid: "binding", route: "app-route", serviceInstance: "service-auth"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cc := newFakeCloudController(t,
+				&fakeRoute{guid: "app-route", host: "app", domain: "example.com", apps: []string{"old-app"}},
+			)
+			cc.bindings = tc.bindings
+
+			release := releaseNewApp(t, Config{Hostname: "app", Domain: "example.com", RouteService: "auth"})
+
+			assert.Equal(t, "service-auth", release.RouteServiceInstanceGuid)
+			if assert.Len(t, cc.bindings, 1) {
+				assert.Equal(t, "app-route", cc.bindings[0].route)
+				assert.Equal(t, "service-auth", cc.bindings[0].serviceInstance)
+			}
+			// The route is only bound once
+			created := 0
+			for _, request := range cc.requests {
+				if request == "POST /v3/service_route_bindings" {
+					created++
+				}
+			}
+			assert.Equal(t, tc.created, created)
+		})
+	}
+}
+
+func TestDestroyUnbindsRouteService(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		routes   []*fakeRoute
+		unbound  bool
+		bindings int
+	}{
+		{
+			name:    "route of the release",
+			routes:  []*fakeRoute{{guid: "app-route", host: "app", domain: "example.com", apps: []string{"app"}}},
+			unbound: true,
+		},
+		{
+			name:     "route shared with a newer release",
+			routes:   []*fakeRoute{{guid: "app-route", host: "app", domain: "example.com", apps: []string{"app", "new-app"}}},
+			bindings: 1,
+		},
+		{
+			name:     "deleted route",
+			bindings: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cc := newFakeCloudController(t, tc.routes...)
+			cc.bindings = []*fakeRouteBinding{{guid: "binding", route: "app-route", serviceInstance: "service-auth"}}
+
+			r := Releaser{}
+			err := r.destroy(
+				context.Background(),
+				hclog.NewNullLogger(),
+				terminal.NonInteractiveUI(context.Background()),
+				&Release{RouteGuid: "app-route", RouteServiceInstanceGuid: "service-auth", AppGuid: "app"},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Len(t, cc.bindings, tc.bindings)
+			if tc.unbound {
+				assert.Equal(t, []string{"DELETE /v3/service_route_bindings/binding"}, cc.requests)
+			} else {
+				assert.Empty(t, cc.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package release
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/platform"
+)
+
+// releaseNewApp releases the new-app deployment with the given configuration
+func releaseNewApp(t *testing.T, config Config) *Release {
+	r := Releaser{config: config}
+	release, err := r.Release(
+		context.Background(),
+		hclog.NewNullLogger(),
+		terminal.NonInteractiveUI(context.Background()),
+		&component.Source{App: "app"},
+		&platform.Deployment{Name: "new-app", AppGUID: "new-app", SpaceGUID: "space", OrganisationGUID: "org"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return release
+}
+
+func TestReleaseMapsRoutes(t *testing.T) {
+	cc := newFakeCloudController(t,
+		&fakeRoute{guid: "app-route", host: "app", domain: "example.com", apps: []string{"old-app"}},
+		&fakeRoute{guid: "api-route", host: "api", domain: "example.org", path: "/v1", apps: []string{"old-app", "new-app"}},
+	)
+
+	release := releaseNewApp(t, Config{
+		Hostname:         "app",
+		Domain:           "example.com",
+		Routes:           []*RouteConfig{{Host: "api", Domain: "example.org", Path: "/v1"}},
+		AdditionalRoutes: []string{"www"},
+	})
+
+	assert.Equal(t, "http://app.example.com", release.Url)
+	assert.Equal(t, "app-route", release.RouteGuid)
+	assert.Equal(t, []string{"app-route", "api-route", "created-route-3"}, release.RouteGuids)
+
+	// The previous app is unmapped from all the routes, which are only mapped to the released app
+	for _, route := range cc.routes {
+		assert.Equal(t, []string{"new-app"}, route.apps, route.url())
+	}
+	assert.Equal(t, "www", cc.routes[2].host)
+	assert.Equal(t, "example.com", cc.routes[2].domain)
+	assert.Contains(t, cc.requests, "DELETE /v3/routes/app-route/destinations/app-route-old-app")
+	assert.Contains(t, cc.requests, "DELETE /v3/routes/api-route/destinations/api-route-old-app")
+	assert.NotContains(t, cc.requests, "DELETE /v3/routes/api-route/destinations/api-route-new-app")
+}
+
+// TestReleaseRouteMatchesExactly checks that the release route is only reused if its host, path and port
+// match, since the route filters of the Cloud Controller don't exclude the other paths and ports
+func TestReleaseRouteMatchesExactly(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		routes   []*fakeRoute
+		route    RouteConfig
+		expected string
+	}{
+		{
+			name:     "only a route with path",
+			routes:   []*fakeRoute{{guid: "path-route", host: "app", domain: "example.com", path: "/api"}},
+			route:    RouteConfig{Host: "app", Domain: "example.com"},
+			expected: "created-route-2",
+		},
+		{
+			name: "routes with and without path",
+			routes: []*fakeRoute{
+				{guid: "path-route", host: "app", domain: "example.com", path: "/api"},
+				{guid: "app-route", host: "app", domain: "example.com"},
+			},
+			route:    RouteConfig{Host: "app", Domain: "example.com"},
+			expected: "app-route",
+		},
+		{
+			name:     "same path",
+			routes:   []*fakeRoute{{guid: "path-route", host: "app", domain: "example.com", path: "/api"}},
+			route:    RouteConfig{Host: "app", Domain: "example.com", Path: "/api"},
+			expected: "path-route",
+		},
+		{
+			name:     "other host",
+			routes:   []*fakeRoute{{guid: "www-route", host: "www", domain: "example.com"}},
+			route:    RouteConfig{Host: "app", Domain: "example.com"},
+			expected: "created-route-2",
+		},
+		{
+			name:     "tcp route",
+			routes:   []*fakeRoute{{guid: "tcp-route", domain: "tcp.example.com", port: 1024}},
+			route:    RouteConfig{Domain: "tcp.example.com", Port: 1024},
+			expected: "tcp-route",
+		},
+		{
+			name:     "other port",
+			routes:   []*fakeRoute{{guid: "tcp-route", domain: "tcp.example.com", port: 1024}},
+			route:    RouteConfig{Domain: "tcp.example.com", Port: 1025},
+			expected: "created-route-2",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cc := newFakeCloudController(t, tc.routes...)
+
+			route := tc.route
+			release := releaseNewApp(t, Config{Routes: []*RouteConfig{&route}})
+
+			assert.Equal(t, tc.expected, release.RouteGuid)
+			created := cc.route(release.RouteGuid)
+			assert.Equal(t, tc.route.Host, created.host)
+			assert.Equal(t, tc.route.Path, created.path)
+			assert.Equal(t, tc.route.Port, created.port)
+			assert.Equal(t, []string{"new-app"}, created.apps)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package release
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	proto "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/platform"
+)
+
+func TestStatusMergesProbe(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		probeStatus int
+		crashed     bool
+		expected    proto.StatusReport_Health
+	}{
+		{name: "failing probe", probeStatus: http.StatusBadGateway, expected: proto.StatusReport_DOWN},
+		{name: "quorum of destinations", probeStatus: http.StatusOK, crashed: true, expected: proto.StatusReport_READY},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cc := newFakeCloudController(t, &fakeRoute{
+				guid:   "route-guid",
+				host:   "app",
+				domain: "example.com",
+				apps:   []string{"app-1", "app-2"},
+			})
+			if tc.crashed {
+				cc.instances["app-2"] = []string{"CRASHED"}
+			}
+
+			releaseUrl := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.probeStatus)
+			}))
+			defer releaseUrl.Close()
+
+			r := Releaser{config: Config{
+				Probe:        &ProbeConfig{},
+				HealthPolicy: &platform.HealthPolicyConfig{Type: "quorum", QuorumPercent: 50},
+			}}
+			report, err := r.Status(
+				context.Background(),
+				hclog.NewNullLogger(),
+				&Release{RouteGuid: "route-guid", Url: releaseUrl.URL},
+				terminal.NonInteractiveUI(context.Background()),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Healthy destinations don't outvote a release URL returning errors
+			assert.Equal(t, tc.expected, report.Health, report.HealthMessage)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ForEachConcurrently calls fn for every index in [0, n), with at most workers calls running at the
+// same time. Once a call fails, the remaining indices are skipped and the first error is returned.
+func ForEachConcurrently(n int, workers int, fn func(i int) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	indices := make(chan int)
+	var failed int32
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				if atomic.LoadInt32(&failed) == 1 {
+					continue
+				}
+				if err := fn(i); err != nil {
+					once.Do(func() { firstErr = err })
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,61 @@
+package utils_test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+func TestForEachConcurrently(t *testing.T) {
+	results := make([]int, 20)
+	err := utils.ForEachConcurrently(len(results), 4, func(i int) error {
+		results[i] = i * i
+		return nil
+	})
+	assert.NoError(t, err)
+	for i, r := range results {
+		assert.Equal(t, i*i, r)
+	}
+}
+
+func TestForEachConcurrentlyBounded(t *testing.T) {
+	var running, maxRunning int32
+	err := utils.ForEachConcurrently(20, 3, func(i int) error {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, maxRunning, int32(3))
+}
+
+func TestForEachConcurrentlyError(t *testing.T) {
+	var calls int32
+	err := utils.ForEachConcurrently(100, 1, func(i int) error {
+		atomic.AddInt32(&calls, 1)
+		if i == 2 {
+			return fmt.Errorf("failed at %d", i)
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "failed at 2")
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestForEachConcurrentlyEmpty(t *testing.T) {
+	assert.NoError(t, utils.ForEachConcurrently(0, 4, func(i int) error {
+		t.Fatal("fn must not be called")
+		return nil
+	}))
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CredentialValue returns the value of a key of service credentials. Nested values are selected with
+// a dot-separated path (e.g. "database.uri"), and non-string values are returned as JSON.
+func CredentialValue(credentials map[string]interface{}, key string) (string, error) {
+	var value interface{} = credentials
+	for _, part := range strings.Split(key, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("key %s not found in credentials", key)
+		}
+		value, ok = object[part]
+		if !ok {
+			return "", fmt.Errorf("key %s not found in credentials", key)
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
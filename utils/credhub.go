@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// CredHubPrefix marks env values that reference a CredHub credential
+const CredHubPrefix = "credhub:"
+
+// ParseCredHubReference parses a reference to a CredHub credential, such as "credhub:/team/app/db-password",
+// optionally followed by the key of a value of the credential, such as "credhub:/team/app/db#password"
+func ParseCredHubReference(value string) (name string, key string, ok bool) {
+	if !strings.HasPrefix(value, CredHubPrefix) {
+		return "", "", false
+	}
+
+	name = strings.TrimPrefix(value, CredHubPrefix)
+	if i := strings.LastIndex(name, "#"); i >= 0 {
+		name, key = name[:i], name[i+1:]
+	}
+	return name, key, name != ""
+}
+
+// CredHubValue returns the value of a CredHub credential as a string. If key is set, the
+// value with that key is returned, see CredentialValue. Objects are returned as JSON.
+func CredHubValue(value interface{}, key string) (string, error) {
+	if key != "" {
+		object, _ := value.(map[string]interface{})
+		return CredentialValue(object, key)
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
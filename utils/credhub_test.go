@@ -0,0 +1,48 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+func TestParseCredHubReference(t *testing.T) {
+	tests := []struct {
+		value string
+		name  string
+		key   string
+		ok    bool
+	}{
+		{"credhub:/team/app/db-password", "/team/app/db-password", "", true},
+		{"credhub:/team/app/db#password", "/team/app/db", "password", true},
+		{"credhub:", "", "", false},
+		{"/team/app/db-password", "", "", false},
+		{"CREDHUB:/team", "", "", false},
+	}
+
+	for _, test := range tests {
+		name, key, ok := utils.ParseCredHubReference(test.value)
+		assert.Equal(t, test.ok, ok, test.value)
+		assert.Equal(t, test.name, name, test.value)
+		assert.Equal(t, test.key, key, test.value)
+	}
+}
+
+func TestCredHubValue(t *testing.T) {
+	value, err := utils.CredHubValue("secret", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", value)
+
+	user := map[string]interface{}{"username": "admin", "password": "secret"}
+	value, err = utils.CredHubValue(user, "password")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", value)
+
+	value, err = utils.CredHubValue(user, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"password":"secret","username":"admin"}`, value)
+
+	_, err = utils.CredHubValue("secret", "password")
+	assert.Error(t, err)
+}
@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultDigestTimeout is the timeout of the requests to the registry when resolving a digest
+const DefaultDigestTimeout = 30 * time.Second
+
+// dockerHubHost is the host of the registry API of Docker Hub
+const dockerHubHost = "registry-1.docker.io"
+
+// manifestMediaTypes are the manifests accepted from the registry. Manifest lists and indexes
+// are preferred, so that the digest covers all platforms of a multi-arch image.
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// IsImageDigest reports whether ref is a content digest, e.g. "sha256:..."
+func IsImageDigest(ref string) bool {
+	return digestPattern.MatchString(ref)
+}
+
+// ImageReference returns the reference of an image at a tag, or at a digest
+func ImageReference(image string, tagOrDigest string) string {
+	if IsImageDigest(tagOrDigest) {
+		return fmt.Sprintf("%s@%s", image, tagOrDigest)
+	}
+	return fmt.Sprintf("%s:%s", image, tagOrDigest)
+}
+
+// DigestOptions configures the requests sent to the registry to resolve a digest
+type DigestOptions struct {
+	// Username and Password authenticate to the registry, anonymous if empty
+	Username string
+	Password string
+	// Insecure uses HTTP instead of HTTPS, e.g. for a local registry
+	Insecure bool
+	// Timeout of each request, DefaultDigestTimeout if 0
+	Timeout time.Duration
+}
+
+// ResolveImageDigest resolves the tag of an image to the content digest of its manifest with the
+// Docker registry v2 API. Token and basic authentication challenges of the registry are supported.
+func ResolveImageDigest(ctx context.Context, image string, tag string, opts DigestOptions) (string, error) {
+	if IsImageDigest(tag) {
+		return tag, nil
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultDigestTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	host, repository := registryRepository(image)
+	scheme := "https"
+	if opts.Insecure {
+		scheme = "http"
+	}
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, host, repository, tag)
+
+	// HEAD doesn't count towards the pull rate limit of Docker Hub, but doesn't always return the digest
+	authorization := ""
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		resp, err := manifestRequest(ctx, client, method, manifestURL, authorization)
+		if err != nil {
+			return "", err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && authorization == "" {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			_ = resp.Body.Close()
+			authorization, err = authenticate(ctx, client, challenge, opts)
+			if err != nil {
+				return "", fmt.Errorf("unable to authenticate to %s: %v", host, err)
+			}
+			resp, err = manifestRequest(ctx, client, method, manifestURL, authorization)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		digest, err := manifestDigest(resp, method == http.MethodGet)
+		if err != nil {
+			return "", fmt.Errorf("unable to get manifest of %s: %v", ImageReference(image, tag), err)
+		}
+		if digest != "" {
+			return digest, nil
+		}
+	}
+	return "", fmt.Errorf("the registry returned no digest for %s", ImageReference(image, tag))
+}
+
+// registryRepository splits an image into the host of its registry API and its repository
+func registryRepository(image string) (host string, repository string) {
+	registry := ImageRegistry(image)
+	repository = strings.TrimPrefix(image, registry+"/")
+	if registry != DockerHubRegistry {
+		return registry, repository
+	}
+	// Official images of Docker Hub are in the library namespace
+	if !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	return dockerHubHost, repository
+}
+
+func manifestRequest(
+	ctx context.Context,
+	client *http.Client,
+	method string,
+	manifestURL string,
+	authorization string,
+) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return client.Do(req)
+}
+
+// manifestDigest returns the digest of a manifest response. On the final attempt, the digest is computed
+// from the body if the registry didn't send it, otherwise an empty digest means trying again with a GET.
+func manifestDigest(resp *http.Response, final bool) (string, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		if !final {
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		if !IsImageDigest(digest) {
+			return "", fmt.Errorf("unsupported digest %s", digest)
+		}
+		return digest, nil
+	}
+	if !final {
+		return "", nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+}
+
+// authenticate answers the authentication challenge of a registry, returning the Authorization header to send
+func authenticate(ctx context.Context, client *http.Client, challenge string, opts DigestOptions) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if opts.Username == "" {
+			return "", fmt.Errorf("the registry requires credentials")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(opts.Username+":"+opts.Password)), nil
+	case "bearer":
+		token, err := registryToken(ctx, client, params, opts)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+}
+
+// registryToken gets a token from the authorization service of a registry, as described by a bearer challenge
+func registryToken(ctx context.Context, client *http.Client, params map[string]string, opts DigestOptions) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid token realm %q", params["realm"])
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if opts.Username != "" {
+		req.SetBasicAuth(opts.Username, opts.Password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid token response: %v", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("the token response has no token")
+}
+
+// parseChallenge parses a WWW-Authenticate header, e.g. `Bearer realm="https://auth.example.com/token",service="registry"`
+func parseChallenge(challenge string) (scheme string, params map[string]string) {
+	params = map[string]string{}
+	challenge = strings.TrimSpace(challenge)
+	i := strings.Index(challenge, " ")
+	if i < 0 {
+		return challenge, params
+	}
+	scheme, rest := challenge[:i], challenge[i+1:]
+
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				value, rest = rest, ""
+			} else {
+				value, rest = rest[:end], rest[end:]
+			}
+		}
+		params[key] = value
+	}
+	return scheme, params
+}
@@ -0,0 +1,87 @@
+package utils_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+// fakeRegistry is a Docker registry serving the manifest of team/app:1.0, behind token authentication
+func fakeRegistry(t *testing.T, sendDigest bool) (*httptest.Server, string) {
+	manifest := `{"schemaVersion":2,"mediaType":"application/vnd.docker.distribution.manifest.list.v2+json"}`
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(manifest)))
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			username, password, ok := r.BasicAuth()
+			if !ok || username != "user" || password != "password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, "registry", r.URL.Query().Get("service"))
+			assert.Equal(t, "repository:team/app:pull", r.URL.Query().Get("scope"))
+			_, _ = w.Write([]byte(`{"token":"registry-token"}`))
+		case "/v2/team/app/manifests/1.0":
+			if r.Header.Get("Authorization") != "Bearer registry-token" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+					`Bearer realm="%s/token",service="registry",scope="repository:team/app:pull"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Contains(t, r.Header.Get("Accept"), "application/vnd.docker.distribution.manifest.list.v2+json")
+			if sendDigest {
+				w.Header().Set("Docker-Content-Digest", digest)
+			}
+			if r.Method == http.MethodGet {
+				_, _ = w.Write([]byte(manifest))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server, digest
+}
+
+func TestResolveImageDigest(t *testing.T) {
+	for _, sendDigest := range []bool{true, false} {
+		t.Run(fmt.Sprintf("digest header %v", sendDigest), func(t *testing.T) {
+			server, expected := fakeRegistry(t, sendDigest)
+			defer server.Close()
+			image := strings.TrimPrefix(server.URL, "http://") + "/team/app"
+
+			opts := utils.DigestOptions{Username: "user", Password: "password", Insecure: true}
+			digest, err := utils.ResolveImageDigest(context.Background(), image, "1.0", opts)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, digest)
+			assert.Equal(t, image+"@"+expected, utils.ImageReference(image, digest))
+
+			_, err = utils.ResolveImageDigest(context.Background(), image, "1.0", utils.DigestOptions{Insecure: true})
+			assert.EqualError(t, err, fmt.Sprintf("unable to authenticate to %s: token request failed with status 401",
+				strings.TrimPrefix(server.URL, "http://")))
+
+			_, err = utils.ResolveImageDigest(context.Background(), image, "2.0", opts)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestImageReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	assert.Equal(t, "nginx:latest", utils.ImageReference("nginx", "latest"))
+	assert.Equal(t, "nginx@"+digest, utils.ImageReference("nginx", digest))
+	assert.True(t, utils.IsImageDigest(digest))
+	assert.False(t, utils.IsImageDigest("sha256:abc"))
+
+	resolved, err := utils.ResolveImageDigest(context.Background(), "nginx", digest, utils.DigestOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, digest, resolved)
+}
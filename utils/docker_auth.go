@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DockerHubRegistry is the registry of the images without a registry host, e.g. "nginx" or "library/nginx"
+const DockerHubRegistry = "docker.io"
+
+// dockerHubAliases are the hosts of Docker Hub found in docker config.json files
+var dockerHubAliases = []string{"docker.io", "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com"}
+
+// ImageRegistry returns the registry host of an image reference, e.g. "registry.example.com:5000"
+// for "registry.example.com:5000/team/app:1.0", or DockerHubRegistry
+func ImageRegistry(image string) string {
+	i := strings.Index(image, "/")
+	if i < 0 {
+		return DockerHubRegistry
+	}
+	host := image[:i]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return DockerHubRegistry
+	}
+	return host
+}
+
+// normalizeRegistry turns a key of a docker config.json (e.g. "https://index.docker.io/v1/")
+// into a registry host
+func normalizeRegistry(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	if i := strings.Index(registry, "/"); i >= 0 {
+		registry = registry[:i]
+	}
+	for _, alias := range dockerHubAliases {
+		if registry == alias {
+			return DockerHubRegistry
+		}
+	}
+	return registry
+}
+
+// DecodeDockerAuth decodes docker credentials encoded in base64 (standard or URL encoding), either as
+// "username:password" or as a JSON object with username and password, like the X-Registry-Auth header
+func DecodeDockerAuth(encoded string) (username string, password string, err error) {
+	encoded = strings.TrimSpace(encoded)
+	var data []byte
+	for _, encoding := range []*base64.Encoding{
+		base64.StdEncoding,
+		base64.URLEncoding,
+		base64.RawStdEncoding,
+		base64.RawURLEncoding,
+	} {
+		data, err = encoding.DecodeString(encoded)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("credentials are not base64 encoded")
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		var auth struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		if err := json.Unmarshal(data, &auth); err != nil {
+			return "", "", fmt.Errorf("invalid JSON credentials: %v", err)
+		}
+		username, password = auth.Username, auth.Password
+	} else {
+		parts := strings.SplitN(strings.TrimSpace(string(data)), ":", 2)
+		if len(parts) != 2 {
+			return "", "", fmt.Errorf("credentials have to be encoded as username:password")
+		}
+		username, password = parts[0], parts[1]
+	}
+
+	if username == "" || password == "" {
+		return "", "", fmt.Errorf("username or password is empty")
+	}
+	return username, password, nil
+}
+
+// dockerConfigFile is the part of a docker config.json holding credentials
+type dockerConfigFile struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+}
+
+// DockerConfigCredentials returns the credentials of registry found in the content of a docker config.json.
+// ok is false if the file has no entry for registry. Credential helpers and stores are not supported.
+func DockerConfigCredentials(content []byte, registry string) (username string, password string, ok bool, err error) {
+	var config dockerConfigFile
+	if err := json.Unmarshal(content, &config); err != nil {
+		return "", "", false, fmt.Errorf("invalid docker config: %v", err)
+	}
+
+	registry = normalizeRegistry(registry)
+	for key, entry := range config.Auths {
+		if normalizeRegistry(key) != registry {
+			continue
+		}
+		if entry.Auth != "" {
+			username, password, err = DecodeDockerAuth(entry.Auth)
+			if err != nil {
+				return "", "", false, fmt.Errorf("invalid auth of %s: %v", key, err)
+			}
+			return username, password, true, nil
+		}
+		if entry.Username != "" && entry.Password != "" {
+			return entry.Username, entry.Password, true, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+// ReadDockerConfigCredentials is like DockerConfigCredentials, reading the docker config.json at path
+func ReadDockerConfigCredentials(path string, registry string) (username string, password string, ok bool, err error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false, err
+	}
+	return DockerConfigCredentials(content, registry)
+}
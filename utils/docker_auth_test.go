@@ -0,0 +1,96 @@
+package utils_test
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+func TestImageRegistry(t *testing.T) {
+	assert.Equal(t, "docker.io", utils.ImageRegistry("nginx"))
+	assert.Equal(t, "docker.io", utils.ImageRegistry("library/nginx"))
+	assert.Equal(t, "registry.example.com", utils.ImageRegistry("registry.example.com/team/app"))
+	assert.Equal(t, "registry.example.com:5000", utils.ImageRegistry("registry.example.com:5000/app:1.0"))
+	assert.Equal(t, "localhost", utils.ImageRegistry("localhost/app"))
+}
+
+func TestDecodeDockerAuth(t *testing.T) {
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	for _, tc := range []struct {
+		name     string
+		encoded  string
+		username string
+		password string
+		err      string
+	}{
+		{name: "username:password", encoded: encode("user:pass:word"), username: "user", password: "pass:word"},
+		{name: "trailing newline", encoded: encode("user:password") + "\n", username: "user", password: "password"},
+		{
+			name:     "JSON",
+			encoded:  base64.URLEncoding.EncodeToString([]byte(`{"username":"user","password":"password"}`)),
+			username: "user",
+			password: "password",
+		},
+		{name: "not base64", encoded: "user:password", err: "credentials are not base64 encoded"},
+		{name: "no password", encoded: encode("user"), err: "credentials have to be encoded as username:password"},
+		{name: "empty password", encoded: encode("user:"), err: "username or password is empty"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			username, password, err := utils.DecodeDockerAuth(tc.encoded)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.username, username)
+			assert.Equal(t, tc.password, password)
+		})
+	}
+}
+
+func TestDockerConfigCredentials(t *testing.T) {
+	config := []byte(`{
+		"auths": {
+			"https://index.docker.io/v1/": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("hub-user:hub-password")) + `"},
+			"registry.example.com": {"username": "user", "password": "password"},
+			"https://other.example.com/v2/": {"auth": "!invalid!"}
+		},
+		"credsStore": "desktop"
+	}`)
+
+	username, password, ok, err := utils.DockerConfigCredentials(config, "docker.io")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "hub-user", username)
+	assert.Equal(t, "hub-password", password)
+
+	username, password, ok, err = utils.DockerConfigCredentials(config, "registry.example.com")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "password", password)
+
+	_, _, ok, err = utils.DockerConfigCredentials(config, "unknown.example.com")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, _, err = utils.DockerConfigCredentials(config, "other.example.com")
+	assert.EqualError(t, err, "invalid auth of https://other.example.com/v2/: credentials are not base64 encoded")
+
+	_, _, _, err = utils.DockerConfigCredentials([]byte("{"), "docker.io")
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, config, 0600))
+	username, _, ok, err = utils.ReadDockerConfigCredentials(path, "registry.example.com")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "user", username)
+}
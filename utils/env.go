@@ -1,24 +1,381 @@
 package utils
 
 import (
-	"regexp"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
 )
 
+// ParseEnv parses the content of a dotenv file, ignoring invalid lines and without
+// interpolating variables.
+//
+// Deprecated: use ParseDotenv, which reports invalid content.
 func ParseEnv(s string) map[string]string {
-	env := map[string]string{}
-	envRegex := regexp.MustCompile("^([^#].*?)=(.*)$")
-	for _, line := range strings.Split(s, "\n") {
-		if !envRegex.MatchString(line) {
-			continue
+	env, _ := ParseDotenv(s, nil)
+	return env
+}
+
+// ReadDotenvFile reads and parses a dotenv file, see ParseDotenv
+func ReadDotenvFile(path string, lookup func(string) (string, bool)) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := ParseDotenv(string(content), lookup)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return env, nil
+}
+
+// ParseDotenv parses the content of a dotenv file. It supports comments, "export" prefixes,
+// single-quoted literal values, double-quoted values with escapes (\n, \t, \", ...), quoted values
+// spanning several lines, and ${VAR} or $VAR interpolation in unquoted and double-quoted values.
+// Variables are resolved from the keys defined earlier in the file, then with lookup, if not nil.
+//
+// On invalid content, the variables parsed until then are returned along with the error.
+func ParseDotenv(s string, lookup func(string) (string, bool)) (map[string]string, error) {
+	p := dotenvParser{input: []rune(s), line: 1, env: map[string]string{}, lookup: lookup}
+	err := p.parse()
+	return p.env, err
+}
+
+type dotenvParser struct {
+	input  []rune
+	pos    int
+	line   int
+	env    map[string]string
+	lookup func(string) (string, bool)
+}
+
+func (p *dotenvParser) parse() error {
+	for {
+		p.skip(func(r rune) bool { return unicode.IsSpace(r) })
+		if p.eof() {
+			return nil
 		}
 
-		matches := envRegex.FindStringSubmatch(line)
-		if len(matches) != 3 {
+		if p.peek() == '#' {
+			p.skipLine()
 			continue
 		}
-		env[matches[1]] = matches[2]
 
+		line := p.line
+		key := p.readKey()
+		if key == "export" {
+			p.skipBlanks()
+			if !p.eof() && p.peek() != '=' {
+				key = p.readKey()
+			}
+		}
+		if key == "" {
+			return fmt.Errorf("line %d: invalid variable name", line)
+		}
+
+		p.skipBlanks()
+		if p.eof() || p.peek() != '=' {
+			return fmt.Errorf("line %d: expected = after %s", line, key)
+		}
+		p.pos++
+		p.skipBlanks()
+
+		value, err := p.readValue()
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		p.env[key] = value
 	}
-	return env
+}
+
+func (p *dotenvParser) eof() bool { return p.pos >= len(p.input) }
+
+func (p *dotenvParser) peek() rune { return p.input[p.pos] }
+
+func (p *dotenvParser) next() rune {
+	r := p.input[p.pos]
+	p.pos++
+	if r == '\n' {
+		p.line++
+	}
+	return r
+}
+
+func (p *dotenvParser) skip(f func(rune) bool) {
+	for !p.eof() && f(p.peek()) {
+		p.next()
+	}
+}
+
+func (p *dotenvParser) skipBlanks() {
+	p.skip(func(r rune) bool { return r == ' ' || r == '\t' })
+}
+
+func (p *dotenvParser) skipLine() {
+	p.skip(func(r rune) bool { return r != '\n' })
+}
+
+func isKeyRune(r rune, first bool) bool {
+	return r == '_' || unicode.IsLetter(r) || (!first && (unicode.IsDigit(r) || r == '.' || r == '-'))
+}
+
+func (p *dotenvParser) readKey() string {
+	start := p.pos
+	for !p.eof() && isKeyRune(p.peek(), p.pos == start) {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+func (p *dotenvParser) readValue() (string, error) {
+	if p.eof() {
+		return "", nil
+	}
+
+	var value string
+	switch quote := p.peek(); quote {
+	case '\'', '"':
+		p.next()
+		start := p.pos
+		closed := false
+		for !p.eof() {
+			r := p.next()
+			if r == quote {
+				closed = true
+				break
+			}
+			if quote == '"' && r == '\\' && !p.eof() {
+				// The escaped rune can't close the value
+				p.next()
+			}
+		}
+		if !closed {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+
+		value = string(p.input[start : p.pos-1])
+		if quote == '"' {
+			value = p.expand(value, true)
+		}
+
+		// Only a comment can follow the closing quote
+		p.skipBlanks()
+		if !p.eof() && p.peek() != '\n' && p.peek() != '\r' && p.peek() != '#' {
+			return "", fmt.Errorf("unexpected characters after quoted value")
+		}
+		p.skipLine()
+	default:
+		start := p.pos
+		p.skipLine()
+		value = string(p.input[start:p.pos])
+		// Inline comments have to be preceded by a blank
+		for i := 1; i < len(value); i++ {
+			if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+				value = value[:i]
+				break
+			}
+		}
+		value = p.expand(strings.TrimSpace(value), false)
+	}
+	return value, nil
+}
+
+// expand replaces ${VAR} and $VAR with the value of the variable. If escapes is true,
+// backslash escapes are replaced too, and \$ is a literal dollar.
+func (p *dotenvParser) expand(value string, escapes bool) string {
+	var sb strings.Builder
+	runes := []rune(value)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escapes && r == '\\' && i+1 < len(runes):
+			i++
+			sb.WriteString(unescape(runes[i]))
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '{':
+			end := strings.IndexRune(string(runes[i+2:]), '}')
+			if end < 0 {
+				sb.WriteString(string(runes[i:]))
+				return sb.String()
+			}
+			name := string(runes[i+2:])[:end]
+			sb.WriteString(p.variable(name))
+			i += 2 + len([]rune(name))
+		case r == '$' && i+1 < len(runes) && isKeyRune(runes[i+1], true):
+			j := i + 1
+			for j < len(runes) && (runes[j] == '_' || unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			sb.WriteString(p.variable(string(runes[i+1 : j])))
+			i = j - 1
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func unescape(r rune) string {
+	switch r {
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 't':
+		return "\t"
+	default:
+		return string(r)
+	}
+}
+
+func (p *dotenvParser) variable(name string) string {
+	if value, ok := p.env[name]; ok {
+		return value
+	}
+	if p.lookup != nil {
+		if value, ok := p.lookup(name); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// ReadEnvFile reads the variables of a JSON (.json), YAML (.yml, .yaml) or dotenv (any other extension)
+// file. JSON and YAML files contain an object, whose nested values are converted to JSON strings. Numbers
+// and other scalars are kept as written. lookup is used to interpolate variables in dotenv files, see
+// ParseDotenv.
+func ReadEnvFile(path string, lookup func(string) (string, bool)) (map[string]string, error) {
+	var values map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// Numbers are kept as written, instead of float64 printed as 1e+06
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if decoder.More() {
+			return nil, fmt.Errorf("%s: invalid content after the top-level object", path)
+		}
+	case ".yml", ".yaml":
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		env, err := yamlEnv(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return env, nil
+	default:
+		return ReadDotenvFile(path, lookup)
+	}
+
+	env := map[string]string{}
+	for k, v := range values {
+		value, err := envValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid value of %s: %v", path, k, err)
+		}
+		env[k] = value
+	}
+	return env, nil
+}
+
+func envValue(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		return fmt.Sprint(value), nil
+	default:
+		data, err := json.Marshal(value)
+		return string(data), err
+	}
+}
+
+// yamlEnv reads the variables of a YAML object. Scalars are kept as written, as decoding them would
+// e.g. print 1.5e7 as 15000000 and 1.50 as 1.5.
+func yamlEnv(content []byte) (map[string]string, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+
+	env := map[string]string{}
+	if len(document.Content) == 0 {
+		return env, nil
+	}
+	object := document.Content[0]
+	if object.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected an object at line %d", object.Line)
+	}
+	for i := 0; i+1 < len(object.Content); i += 2 {
+		key, value := object.Content[i], object.Content[i+1]
+		if key.Tag == "!!merge" {
+			return nil, fmt.Errorf("merge keys aren't supported, at line %d", key.Line)
+		}
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+
+		switch {
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+			env[key.Value] = ""
+		case value.Kind == yaml.ScalarNode:
+			env[key.Value] = value.Value
+		default:
+			var nested interface{}
+			if err := value.Decode(&nested); err != nil {
+				return nil, err
+			}
+			data, err := json.Marshal(nested)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of %s: %v", key.Value, err)
+			}
+			env[key.Value] = string(data)
+		}
+	}
+	return env, nil
+}
+
+// EnvLayer is a set of environment variables coming from a source, such as a file
+type EnvLayer struct {
+	Source string
+	Env    map[string]string
+}
+
+// MergeEnv merges layers of environment variables, later layers taking precedence over earlier ones.
+// It returns the merged variables, and the source each variable comes from.
+func MergeEnv(layers ...EnvLayer) (env map[string]string, sources map[string]string) {
+	env = map[string]string{}
+	sources = map[string]string{}
+	for _, layer := range layers {
+		for k, v := range layer.Env {
+			env[k] = v
+			sources[k] = layer.Source
+		}
+	}
+	return env, sources
+}
+
+// MaskValue hides a secret value, so that it can be logged. Empty values are kept empty.
+func MaskValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return RedactedValue
 }
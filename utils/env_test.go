@@ -1,6 +1,8 @@
 package utils_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,3 +15,134 @@ func TestParseEnv(t *testing.T) {
 	assert.Equal(t, "none", envs["KUBECONFIG"])
 	assert.Len(t, envs, 2)
 }
+
+func TestParseDotenv(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "RUNNER_VAR" {
+			return "from-runner", true
+		}
+		return "", false
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+	}{
+		{"empty", "", map[string]string{}},
+		{"simple", "A=1\nB=two", map[string]string{"A": "1", "B": "two"}},
+		{"comments and blank lines", "# comment\n\n  # indented\nA=1\n", map[string]string{"A": "1"}},
+		{"spaces around equal sign", "A = 1 ", map[string]string{"A": "1"}},
+		{"empty value", "A=\nB=", map[string]string{"A": "", "B": ""}},
+		{"export prefix", "export A=1\nexport\tB=2\nexport = 3", map[string]string{"A": "1", "B": "2", "export": "3"}},
+		{"inline comment", "A=1 # comment\nB=a#b", map[string]string{"A": "1", "B": "a#b"}},
+		{"windows line endings", "A=1\r\nB=2\r\n", map[string]string{"A": "1", "B": "2"}},
+		{"double quotes", `A="hello world" # comment`, map[string]string{"A": "hello world"}},
+		{"double quotes escapes", `A="line1\nline2\t\"quoted\"\\"`, map[string]string{"A": "line1\nline2\t\"quoted\"\\"}},
+		{"single quotes are literal", `A='no\nescape $B'`, map[string]string{"A": `no\nescape $B`}},
+		{"hash in quotes", `A="a # b"`, map[string]string{"A": "a # b"}},
+		{"multi-line double quotes", "A=\"first\nsecond\"\nB=3", map[string]string{"A": "first\nsecond", "B": "3"}},
+		{"multi-line single quotes", "A='-----BEGIN KEY-----\nabc\n-----END KEY-----'", map[string]string{"A": "-----BEGIN KEY-----\nabc\n-----END KEY-----"}},
+		{"interpolation", "A=1\nB=${A}-$A\nC=\"${B}\"", map[string]string{"A": "1", "B": "1-1", "C": "1-1"}},
+		{"interpolation from runner", "A=$RUNNER_VAR", map[string]string{"A": "from-runner"}},
+		{"earlier keys win over runner", "RUNNER_VAR=local\nA=$RUNNER_VAR", map[string]string{"RUNNER_VAR": "local", "A": "local"}},
+		{"undefined variables are empty", "A=x${UNDEFINED}y", map[string]string{"A": "xy"}},
+		{"escaped dollar", `A="\$HOME"`, map[string]string{"A": "$HOME"}},
+		{"unterminated brace", "A=${B", map[string]string{"A": "${B"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := utils.ParseDotenv(test.input, lookup)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, env)
+		})
+	}
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"unterminated double quotes", "A=1\nB=\"value", "line 2: unterminated quoted value"},
+		{"unterminated single quotes", "A='value", "line 1: unterminated quoted value"},
+		{"missing equal sign", "A=1\nB 2", "line 2: expected = after B"},
+		{"invalid name", "1A=1", "line 1: invalid variable name"},
+		{"text after quotes", `A="value" text`, "line 1: unexpected characters after quoted value"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := utils.ParseDotenv(test.input, nil)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestReadDotenvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	assert.NoError(t, os.WriteFile(path, []byte("export A=\"1\"\n"), 0600))
+
+	env, err := utils.ReadDotenvFile(path, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "1"}, env)
+
+	_, err = utils.ReadDotenvFile(filepath.Join(t.TempDir(), "missing"), nil)
+	assert.Error(t, err)
+}
+
+func TestReadEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.json": `{"A": "1", "B": 2, "C": true, "D": {"nested": ["x"]}, "E": null, "F": 1000000, "G": 1.50}`,
+		"app.yml":  "A: '1'\nB: 2\nC: true\nD:\n  nested: [x]\nE: ~\nF: 1000000\nG: 1.5e7\nH: 1.50\nI: &port 0x1F90\nJ: *port\n",
+		"app.env":  "A=1\nB=$RUNNER",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+
+	env, err := utils.ReadEnvFile(filepath.Join(dir, "app.json"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"A": "1", "B": "2", "C": "true", "D": `{"nested":["x"]}`, "E": "", "F": "1000000", "G": "1.50",
+	}, env)
+
+	env, err = utils.ReadEnvFile(filepath.Join(dir, "app.yml"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"A": "1", "B": "2", "C": "true", "D": `{"nested":["x"]}`, "E": "", "F": "1000000", "G": "1.5e7", "H": "1.50",
+		"I": "0x1F90", "J": "0x1F90",
+	}, env)
+
+	env, err = utils.ReadEnvFile(filepath.Join(dir, "app.env"), func(name string) (string, bool) {
+		return "runner", name == "RUNNER"
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "1", "B": "runner"}, env)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.json"), []byte("[1, 2]"), 0600))
+	_, err = utils.ReadEnvFile(filepath.Join(dir, "invalid.json"), nil)
+	assert.Error(t, err)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.yml"), []byte("- 1\n- 2\n"), 0600))
+	_, err = utils.ReadEnvFile(filepath.Join(dir, "invalid.yml"), nil)
+	assert.Error(t, err)
+}
+
+func TestMergeEnv(t *testing.T) {
+	env, sources := utils.MergeEnv(
+		utils.EnvLayer{Source: "base.env", Env: map[string]string{"A": "1", "B": "1"}},
+		utils.EnvLayer{Source: "prod.json", Env: map[string]string{"B": "2", "C": "2"}},
+		utils.EnvLayer{Source: "env", Env: map[string]string{"C": "3"}},
+	)
+	assert.Equal(t, map[string]string{"A": "1", "B": "2", "C": "3"}, env)
+	assert.Equal(t, map[string]string{"A": "base.env", "B": "prod.json", "C": "env"}, sources)
+}
+
+func TestMaskValue(t *testing.T) {
+	assert.Equal(t, "", utils.MaskValue(""))
+	assert.Equal(t, "********", utils.MaskValue("secret"))
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	AppProcessCrashEvent = "audit.app.process.crash"
+	AppUpdateEvent       = "audit.app.update"
+)
+
+// IsRelevantAppEvent tells whether an audit event explains the state of a deployment:
+// process crashes, droplet changes and app updates
+func IsRelevantAppEvent(eventType string) bool {
+	return eventType == AppProcessCrashEvent ||
+		eventType == AppUpdateEvent ||
+		strings.HasPrefix(strings.TrimPrefix(eventType, "audit."), "app.droplet.")
+}
+
+// DescribeAppEvent returns a short description of an app audit event, given its type and data
+func DescribeAppEvent(eventType string, data map[string]interface{}) string {
+	switch {
+	case eventType == AppProcessCrashEvent:
+		description := fmt.Sprintf("instance %v crashed", data["index"])
+		if exit, ok := data["exit_description"].(string); ok && exit != "" {
+			description += ": " + exit
+		}
+		if reason, ok := data["reason"].(string); ok && reason != "" {
+			description += fmt.Sprintf(" (%s)", reason)
+		}
+		return description
+	case eventType == AppUpdateEvent:
+		request, _ := data["request"].(map[string]interface{})
+		if len(request) == 0 {
+			return "app updated"
+		}
+		return "app updated: " + describeRequest(request)
+	case strings.Contains(eventType, "app.droplet."):
+		return "droplet " + eventType[strings.LastIndex(eventType, ".")+1:]
+	default:
+		return eventType
+	}
+}
+
+// describeRequest lists the fields of an update request, with their value if it's a scalar
+func describeRequest(request map[string]interface{}) string {
+	var fields []string
+	for key, value := range request {
+		switch value.(type) {
+		case string, float64, bool:
+			fields = append(fields, fmt.Sprintf("%s=%v", key, value))
+		default:
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,39 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+func TestIsRelevantAppEvent(t *testing.T) {
+	assert.True(t, utils.IsRelevantAppEvent("audit.app.process.crash"))
+	assert.True(t, utils.IsRelevantAppEvent("audit.app.droplet.mapped"))
+	assert.True(t, utils.IsRelevantAppEvent("audit.app.droplet.create"))
+	assert.True(t, utils.IsRelevantAppEvent("audit.app.update"))
+	assert.False(t, utils.IsRelevantAppEvent("audit.app.create"))
+	assert.False(t, utils.IsRelevantAppEvent("audit.app.map-route"))
+}
+
+func TestDescribeAppEvent(t *testing.T) {
+	assert.Equal(t,
+		"instance 1 crashed: APP/PROC/WEB: Exited with status 1 (CRASHED)",
+		utils.DescribeAppEvent("audit.app.process.crash", map[string]interface{}{
+			"index":            float64(1),
+			"exit_description": "APP/PROC/WEB: Exited with status 1",
+			"reason":           "CRASHED",
+		}),
+	)
+	assert.Equal(t, "droplet mapped", utils.DescribeAppEvent("audit.app.droplet.mapped", nil))
+	assert.Equal(t, "app updated", utils.DescribeAppEvent("audit.app.update", map[string]interface{}{}))
+	assert.Equal(t,
+		"app updated: environment_variables, state=STOPPED",
+		utils.DescribeAppEvent("audit.app.update", map[string]interface{}{
+			"request": map[string]interface{}{
+				"state":                 "STOPPED",
+				"environment_variables": map[string]interface{}{"FOO": "[PRIVATE DATA HIDDEN]"},
+			},
+		}),
+	)
+}
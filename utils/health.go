@@ -1,48 +1,83 @@
 package utils
 
 import (
-	proto "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
+	"fmt"
 	"strings"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3/constant"
+	proto "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
 )
 
 func HealthSummary(statusReport ...*proto.StatusReport) *proto.StatusReport {
+	return HealthSummaryWithPolicy(WorstOf{}, statusReport...)
+}
+
+// HealthSummaryWithPolicy merges several status reports, aggregating their health with policy
+func HealthSummaryWithPolicy(policy HealthPolicy, statusReport ...*proto.StatusReport) *proto.StatusReport {
 	if statusReport == nil {
 		return nil
 	}
 
 	result := proto.StatusReport{}
-	result.Health = proto.StatusReport_READY
 	var outMessage []string
+	var samples []HealthSample
 
 	for _, r := range statusReport {
 		outMessage = append(outMessage, r.HealthMessage)
-		if isWorse(r.Health, result.Health){
-			result.Health = r.Health
-		}
+		samples = append(samples, HealthSample{Health: r.Health})
 	}
 
+	result.Health = policy.Aggregate(samples)
 	result.HealthMessage = strings.Join(outMessage, ", ")
 	result.External = statusReport[0].External
+	for _, r := range statusReport {
+		result.Resources = append(result.Resources, r.Resources...)
+	}
 	return &result
 }
 
-func isWorse(health1 proto.StatusReport_Health, health2 proto.StatusReport_Health) bool {
-	goodToWorse := []proto.StatusReport_Health{
-		proto.StatusReport_READY, proto.StatusReport_ALIVE,
-		proto.StatusReport_PARTIAL, proto.StatusReport_DOWN,
-		proto.StatusReport_UNKNOWN,
+// InstanceHealth maps the state of a process instance to a health
+func InstanceHealth(state constant.ProcessInstanceState) proto.StatusReport_Health {
+	switch state {
+	case constant.ProcessInstanceRunning:
+		return proto.StatusReport_READY
+	case constant.ProcessInstanceStarting:
+		return proto.StatusReport_ALIVE
+	case constant.ProcessInstanceCrashed, constant.ProcessInstanceDown:
+		return proto.StatusReport_DOWN
+	default:
+		return proto.StatusReport_UNKNOWN
 	}
+}
 
-	var indices = make([]int, 2)
+// InstancesHealth returns the health of a group of process instances, given their states
+func InstancesHealth(states []constant.ProcessInstanceState) (proto.StatusReport_Health, string) {
+	statusMap := map[constant.ProcessInstanceState]int{}
+	for _, state := range states {
+		statusMap[state]++
+	}
 
-	for i, v := range goodToWorse {
-		if v == health1 {
-			indices[0] = i
-		}
-		if v == health2 {
-			indices[1] = i
-		}
+	switch len(states) {
+	case statusMap[constant.ProcessInstanceRunning]:
+		return proto.StatusReport_READY, "all processes are reporting ready"
+	case statusMap[constant.ProcessInstanceCrashed]:
+		return proto.StatusReport_DOWN, "all processes are crashed"
+	case statusMap[constant.ProcessInstanceStarting]:
+		return proto.StatusReport_ALIVE, "all processes are starting"
+	case statusMap[constant.ProcessInstanceDown]:
+		return proto.StatusReport_DOWN, "all processes are reporting down"
+	default:
+		return proto.StatusReport_PARTIAL, fmt.Sprintf("all processes are reporting mixed status: %v", statusMap)
 	}
+}
 
-	return indices[0] >= indices[1]
+// InstancesHealthWithPolicy returns the health of a group of process instances, aggregated with policy
+func InstancesHealthWithPolicy(policy HealthPolicy, samples []HealthSample) (proto.StatusReport_Health, string) {
+	ready := 0
+	for _, s := range samples {
+		if s.Health == proto.StatusReport_READY {
+			ready++
+		}
+	}
+	return policy.Aggregate(samples), fmt.Sprintf("%d of %d instances ready (%s)", ready, len(samples), policy)
 }
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	proto "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
+)
+
+// HealthSample is the health of an instance or of a report, as seen by a HealthPolicy
+type HealthSample struct {
+	Health proto.StatusReport_Health
+	// Age is how long the instance has been in its current state, 0 if unknown
+	Age time.Duration
+}
+
+// HealthPolicy aggregates several health samples into a single health
+type HealthPolicy interface {
+	Aggregate(samples []HealthSample) proto.StatusReport_Health
+	String() string
+}
+
+// healthRank orders healths from the best to the worst
+var healthRank = map[proto.StatusReport_Health]int{
+	proto.StatusReport_READY:   0,
+	proto.StatusReport_ALIVE:   1,
+	proto.StatusReport_PARTIAL: 2,
+	proto.StatusReport_DOWN:    3,
+	proto.StatusReport_MISSING: 4,
+	proto.StatusReport_UNKNOWN: 5,
+}
+
+func rank(health proto.StatusReport_Health) int {
+	if r, ok := healthRank[health]; ok {
+		return r
+	}
+	// Values added to the protocol later are handled like UNKNOWN
+	return healthRank[proto.StatusReport_UNKNOWN]
+}
+
+// WorstOf reports the worst health of all samples, READY if there are none
+type WorstOf struct{}
+
+func (WorstOf) Aggregate(samples []HealthSample) proto.StatusReport_Health {
+	result := proto.StatusReport_READY
+	for _, s := range samples {
+		if rank(s.Health) > rank(result) {
+			result = s.Health
+		}
+	}
+	return result
+}
+
+func (WorstOf) String() string { return "worst-of" }
+
+// Quorum reports READY if at least Percent of the samples are READY. Otherwise, it reports
+// PARTIAL if some samples are READY, and the worst health of all samples if none is.
+type Quorum struct {
+	Percent int
+}
+
+func (q Quorum) Aggregate(samples []HealthSample) proto.StatusReport_Health {
+	if len(samples) == 0 {
+		return proto.StatusReport_READY
+	}
+
+	ready := 0
+	for _, s := range samples {
+		if s.Health == proto.StatusReport_READY {
+			ready++
+		}
+	}
+
+	switch {
+	case ready*100 >= q.Percent*len(samples):
+		return proto.StatusReport_READY
+	case ready > 0:
+		return proto.StatusReport_PARTIAL
+	default:
+		return WorstOf{}.Aggregate(samples)
+	}
+}
+
+func (q Quorum) String() string { return fmt.Sprintf("quorum of %d%% ready", q.Percent) }
+
+// StartingGracePeriod ignores samples that have been ALIVE (starting) for less than Period,
+// and aggregates the other ones with Policy. If all samples are ignored, it reports ALIVE.
+type StartingGracePeriod struct {
+	Period time.Duration
+	Policy HealthPolicy
+}
+
+func (g StartingGracePeriod) Aggregate(samples []HealthSample) proto.StatusReport_Health {
+	var relevant []HealthSample
+	for _, s := range samples {
+		if s.Health == proto.StatusReport_ALIVE && s.Age < g.Period {
+			continue
+		}
+		relevant = append(relevant, s)
+	}
+
+	if len(relevant) == 0 && len(samples) > 0 {
+		return proto.StatusReport_ALIVE
+	}
+	return g.Policy.Aggregate(relevant)
+}
+
+func (g StartingGracePeriod) String() string {
+	return fmt.Sprintf("%s, ignoring instances starting for less than %s", g.Policy, g.Period)
+}
+
+// NewHealthPolicy returns the policy with the given name ("worst-of" or "quorum"), optionally
+// ignoring instances that are starting for less than gracePeriod
+func NewHealthPolicy(name string, quorumPercent int, gracePeriod time.Duration) (HealthPolicy, error) {
+	var policy HealthPolicy
+	switch name {
+	case "", "worst-of":
+		policy = WorstOf{}
+	case "quorum":
+		if quorumPercent < 1 || quorumPercent > 100 {
+			return nil, fmt.Errorf("quorum_percent must be in range 1-100, got %d", quorumPercent)
+		}
+		policy = Quorum{Percent: quorumPercent}
+	default:
+		return nil, fmt.Errorf("unknown health policy %q, must be worst-of or quorum", name)
+	}
+
+	if gracePeriod < 0 {
+		return nil, fmt.Errorf("grace period cannot be negative")
+	}
+	if gracePeriod > 0 {
+		policy = StartingGracePeriod{Period: gracePeriod, Policy: policy}
+	}
+	return policy, nil
+}
@@ -0,0 +1,127 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	proto "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+func policySamples(healths ...proto.StatusReport_Health) []utils.HealthSample {
+	var result []utils.HealthSample
+	for _, h := range healths {
+		result = append(result, utils.HealthSample{Health: h})
+	}
+	return result
+}
+
+func TestWorstOf(t *testing.T) {
+	tests := []struct {
+		samples  []utils.HealthSample
+		expected proto.StatusReport_Health
+	}{
+		{nil, proto.StatusReport_READY},
+		{policySamples(proto.StatusReport_READY, proto.StatusReport_READY), proto.StatusReport_READY},
+		{policySamples(proto.StatusReport_READY, proto.StatusReport_ALIVE), proto.StatusReport_ALIVE},
+		{policySamples(proto.StatusReport_ALIVE, proto.StatusReport_PARTIAL), proto.StatusReport_PARTIAL},
+		{policySamples(proto.StatusReport_PARTIAL, proto.StatusReport_DOWN, proto.StatusReport_READY), proto.StatusReport_DOWN},
+		{policySamples(proto.StatusReport_DOWN, proto.StatusReport_MISSING), proto.StatusReport_MISSING},
+		{policySamples(proto.StatusReport_MISSING, proto.StatusReport_UNKNOWN, proto.StatusReport_READY), proto.StatusReport_UNKNOWN},
+		{policySamples(proto.StatusReport_READY, proto.StatusReport_Health(42)), proto.StatusReport_Health(42)},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, utils.WorstOf{}.Aggregate(test.samples), "%v", test.samples)
+	}
+}
+
+func TestQuorum(t *testing.T) {
+	tests := []struct {
+		percent  int
+		samples  []utils.HealthSample
+		expected proto.StatusReport_Health
+	}{
+		{90, nil, proto.StatusReport_READY},
+		{90, policySamples(
+			proto.StatusReport_READY, proto.StatusReport_READY, proto.StatusReport_READY, proto.StatusReport_READY,
+			proto.StatusReport_READY, proto.StatusReport_READY, proto.StatusReport_READY, proto.StatusReport_READY,
+			proto.StatusReport_READY, proto.StatusReport_ALIVE,
+		), proto.StatusReport_READY},
+		{90, policySamples(
+			proto.StatusReport_READY, proto.StatusReport_READY, proto.StatusReport_READY, proto.StatusReport_READY,
+			proto.StatusReport_READY, proto.StatusReport_READY, proto.StatusReport_READY, proto.StatusReport_READY,
+			proto.StatusReport_ALIVE, proto.StatusReport_DOWN,
+		), proto.StatusReport_PARTIAL},
+		{50, policySamples(proto.StatusReport_READY, proto.StatusReport_DOWN), proto.StatusReport_READY},
+		{100, policySamples(proto.StatusReport_READY, proto.StatusReport_PARTIAL), proto.StatusReport_PARTIAL},
+		{50, policySamples(proto.StatusReport_ALIVE, proto.StatusReport_ALIVE), proto.StatusReport_ALIVE},
+		{50, policySamples(proto.StatusReport_ALIVE, proto.StatusReport_DOWN), proto.StatusReport_DOWN},
+		{50, policySamples(proto.StatusReport_MISSING, proto.StatusReport_UNKNOWN), proto.StatusReport_UNKNOWN},
+		{1, policySamples(proto.StatusReport_READY, proto.StatusReport_UNKNOWN, proto.StatusReport_UNKNOWN), proto.StatusReport_READY},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, utils.Quorum{Percent: test.percent}.Aggregate(test.samples),
+			"%d%% of %v", test.percent, test.samples)
+	}
+}
+
+func TestStartingGracePeriod(t *testing.T) {
+	policy := utils.StartingGracePeriod{Period: time.Minute, Policy: utils.WorstOf{}}
+
+	recentlyStarting := utils.HealthSample{Health: proto.StatusReport_ALIVE, Age: 10 * time.Second}
+	longStarting := utils.HealthSample{Health: proto.StatusReport_ALIVE, Age: 2 * time.Minute}
+
+	assert.Equal(t, proto.StatusReport_READY, policy.Aggregate(nil))
+	ready := policySamples(proto.StatusReport_READY, proto.StatusReport_READY)
+	assert.Equal(t, proto.StatusReport_READY, policy.Aggregate(append(ready, recentlyStarting)))
+	assert.Equal(t, proto.StatusReport_ALIVE, policy.Aggregate(append(ready, longStarting)))
+	assert.Equal(t, proto.StatusReport_ALIVE, policy.Aggregate([]utils.HealthSample{recentlyStarting}))
+	assert.Equal(t, proto.StatusReport_DOWN, policy.Aggregate(append(policySamples(proto.StatusReport_DOWN), recentlyStarting)))
+
+	// Only ALIVE samples are ignored, whatever their age
+	for _, health := range []proto.StatusReport_Health{
+		proto.StatusReport_PARTIAL, proto.StatusReport_DOWN, proto.StatusReport_MISSING, proto.StatusReport_UNKNOWN,
+	} {
+		sample := utils.HealthSample{Health: health, Age: time.Second}
+		assert.Equal(t, health, policy.Aggregate(append(policySamples(proto.StatusReport_READY), sample)))
+	}
+}
+
+func TestNewHealthPolicy(t *testing.T) {
+	policy, err := utils.NewHealthPolicy("", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.WorstOf{}, policy)
+
+	policy, err = utils.NewHealthPolicy("quorum", 80, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.StartingGracePeriod{Period: time.Minute, Policy: utils.Quorum{Percent: 80}}, policy)
+	assert.Equal(t, "quorum of 80% ready, ignoring instances starting for less than 1m0s", policy.String())
+
+	_, err = utils.NewHealthPolicy("quorum", 0, 0)
+	assert.Error(t, err)
+
+	_, err = utils.NewHealthPolicy("best-of", 0, 0)
+	assert.Error(t, err)
+
+	_, err = utils.NewHealthPolicy("worst-of", 0, -time.Second)
+	assert.Error(t, err)
+}
+
+func TestHealthSummaryWithPolicy(t *testing.T) {
+	summary := utils.HealthSummaryWithPolicy(utils.Quorum{Percent: 50},
+		&proto.StatusReport{Health: proto.StatusReport_READY, HealthMessage: "app 1 ready"},
+		&proto.StatusReport{Health: proto.StatusReport_DOWN, HealthMessage: "app 2 down"},
+	)
+	assert.Equal(t, proto.StatusReport_READY, summary.Health)
+	assert.Equal(t, "app 1 ready, app 2 down", summary.HealthMessage)
+}
+
+func TestInstancesHealthWithPolicy(t *testing.T) {
+	samples := policySamples(proto.StatusReport_READY, proto.StatusReport_READY, proto.StatusReport_ALIVE)
+	health, message := utils.InstancesHealthWithPolicy(utils.Quorum{Percent: 90}, samples)
+	assert.Equal(t, proto.StatusReport_PARTIAL, health)
+	assert.Equal(t, "2 of 3 instances ready (quorum of 90% ready)", message)
+}
@@ -1,6 +1,7 @@
 package utils_test
 
 import (
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3/constant"
 	proto "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
 	"github.com/stretchr/testify/assert"
 	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
@@ -30,3 +31,51 @@ func TestHealthSummary(t *testing.T) {
 		utils.HealthSummary(&report1, &report2),
 	)
 }
+
+func TestHealthSummaryResources(t *testing.T) {
+	report1 := proto.StatusReport{
+		Health:    proto.StatusReport_READY,
+		Resources: []*proto.StatusReport_Resource{{Id: "app-1"}},
+	}
+
+	report2 := proto.StatusReport{
+		Health:    proto.StatusReport_READY,
+		Resources: []*proto.StatusReport_Resource{{Id: "app-2"}, {Id: "process-2"}},
+	}
+
+	summary := utils.HealthSummary(&report1, &report2)
+	assert.Len(t, summary.Resources, 3)
+	assert.Equal(t, "app-1", summary.Resources[0].Id)
+	assert.Equal(t, "process-2", summary.Resources[2].Id)
+}
+
+func TestInstancesHealth(t *testing.T) {
+	health, _ := utils.InstancesHealth([]constant.ProcessInstanceState{
+		constant.ProcessInstanceRunning, constant.ProcessInstanceRunning,
+	})
+	assert.Equal(t, proto.StatusReport_READY, health)
+
+	health, _ = utils.InstancesHealth([]constant.ProcessInstanceState{
+		constant.ProcessInstanceCrashed, constant.ProcessInstanceCrashed,
+	})
+	assert.Equal(t, proto.StatusReport_DOWN, health)
+
+	health, _ = utils.InstancesHealth([]constant.ProcessInstanceState{
+		constant.ProcessInstanceStarting,
+	})
+	assert.Equal(t, proto.StatusReport_ALIVE, health)
+
+	health, message := utils.InstancesHealth([]constant.ProcessInstanceState{
+		constant.ProcessInstanceRunning, constant.ProcessInstanceCrashed,
+	})
+	assert.Equal(t, proto.StatusReport_PARTIAL, health)
+	assert.Equal(t, "all processes are reporting mixed status: map[CRASHED:1 RUNNING:1]", message)
+}
+
+func TestInstanceHealth(t *testing.T) {
+	assert.Equal(t, proto.StatusReport_READY, utils.InstanceHealth(constant.ProcessInstanceRunning))
+	assert.Equal(t, proto.StatusReport_ALIVE, utils.InstanceHealth(constant.ProcessInstanceStarting))
+	assert.Equal(t, proto.StatusReport_DOWN, utils.InstanceHealth(constant.ProcessInstanceCrashed))
+	assert.Equal(t, proto.StatusReport_DOWN, utils.InstanceHealth(constant.ProcessInstanceDown))
+	assert.Equal(t, proto.StatusReport_UNKNOWN, utils.InstanceHealth("UNKNOWN"))
+}
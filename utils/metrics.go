@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultUsageThresholdPercent is the share of a quota above which an instance is flagged
+const DefaultUsageThresholdPercent = 90
+
+// QuotaUsageMessage explains which resources of an instance use more than thresholdPercent of
+// their quota, or returns an empty string if none does. A quota of 0 means no quota is known.
+func QuotaUsageMessage(memory, memoryQuota, disk, diskQuota uint64, thresholdPercent int) string {
+	var messages []string
+	if m := usageMessage("memory", memory, memoryQuota, thresholdPercent); m != "" {
+		messages = append(messages, m)
+	}
+	if m := usageMessage("disk", disk, diskQuota, thresholdPercent); m != "" {
+		messages = append(messages, m)
+	}
+	return strings.Join(messages, ", ")
+}
+
+func usageMessage(name string, usage, quota uint64, thresholdPercent int) string {
+	if quota == 0 {
+		return ""
+	}
+	percent := float64(usage) * 100 / float64(quota)
+	if percent < float64(thresholdPercent) {
+		return ""
+	}
+	return fmt.Sprintf("%s usage at %.0f%% of quota (%d of %d MB)", name, percent, usage>>20, quota>>20)
+}
@@ -0,0 +1,26 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+const mb = 1 << 20
+
+func TestQuotaUsageMessage(t *testing.T) {
+	assert.Equal(t, "", utils.QuotaUsageMessage(100*mb, 1024*mb, 100*mb, 1024*mb, 90))
+	assert.Equal(t,
+		"memory usage at 95% of quota (973 of 1024 MB)",
+		utils.QuotaUsageMessage(973*mb, 1024*mb, 100*mb, 1024*mb, 90),
+	)
+	assert.Equal(t,
+		"memory usage at 50% of quota (512 of 1024 MB), disk usage at 100% of quota (1024 of 1024 MB)",
+		utils.QuotaUsageMessage(512*mb, 1024*mb, 1024*mb, 1024*mb, 50),
+	)
+}
+
+func TestQuotaUsageMessageUnknownQuota(t *testing.T) {
+	assert.Equal(t, "", utils.QuotaUsageMessage(512*mb, 0, 512*mb, 0, 90))
+}
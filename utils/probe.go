@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	proto "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
+)
+
+// DefaultProbeTimeout is used when ProbeOptions.Timeout is not set
+const DefaultProbeTimeout = 10 * time.Second
+
+// ProbeOptions configures the HTTP probe of ProbeURL
+type ProbeOptions struct {
+	// ExpectedStatus lists the status codes considered healthy, any 2xx or 3xx if empty
+	ExpectedStatus     []int
+	Timeout            time.Duration
+	InsecureSkipVerify bool
+}
+
+// ProbeURL sends a GET request to url and reports it READY if the response has an expected status code,
+// DOWN otherwise. Redirects are not followed, so that the status code of the route itself is checked.
+func ProbeURL(ctx context.Context, url string, options ProbeOptions) *proto.StatusReport {
+	result := &proto.StatusReport{External: true}
+
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = DefaultProbeTimeout
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: options.InsecureSkipVerify},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		result.Health = proto.StatusReport_UNKNOWN
+		result.HealthMessage = fmt.Sprintf("unable to probe %s: %v", url, err)
+		return result
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Health = proto.StatusReport_DOWN
+		result.HealthMessage = fmt.Sprintf("%s is unreachable: %v", url, err)
+		return result
+	}
+	_ = resp.Body.Close()
+
+	if isExpectedStatus(resp.StatusCode, options.ExpectedStatus) {
+		result.Health = proto.StatusReport_READY
+		result.HealthMessage = fmt.Sprintf("%s responded with %d", url, resp.StatusCode)
+	} else {
+		result.Health = proto.StatusReport_DOWN
+		result.HealthMessage = fmt.Sprintf("%s responded with unexpected status %d", url, resp.StatusCode)
+	}
+	return result
+}
+
+func isExpectedStatus(status int, expected []int) bool {
+	if len(expected) == 0 {
+		return status >= 200 && status < 400
+	}
+	for _, e := range expected {
+		if status == e {
+			return true
+		}
+	}
+	return false
+}
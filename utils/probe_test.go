@@ -0,0 +1,63 @@
+package utils_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	proto "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+func TestProbeURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.WriteHeader(http.StatusOK)
+		case "/redirect":
+			http.Redirect(w, r, "/health", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	report := utils.ProbeURL(context.Background(), server.URL+"/health", utils.ProbeOptions{})
+	assert.Equal(t, proto.StatusReport_READY, report.Health)
+	assert.Equal(t, server.URL+"/health responded with 200", report.HealthMessage)
+
+	report = utils.ProbeURL(context.Background(), server.URL+"/missing", utils.ProbeOptions{})
+	assert.Equal(t, proto.StatusReport_DOWN, report.Health)
+	assert.Equal(t, server.URL+"/missing responded with unexpected status 502", report.HealthMessage)
+
+	report = utils.ProbeURL(context.Background(), server.URL+"/redirect", utils.ProbeOptions{ExpectedStatus: []int{200}})
+	assert.Equal(t, proto.StatusReport_DOWN, report.Health)
+
+	report = utils.ProbeURL(context.Background(), server.URL+"/missing", utils.ProbeOptions{ExpectedStatus: []int{502}})
+	assert.Equal(t, proto.StatusReport_READY, report.Health)
+}
+
+func TestProbeURLTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	report := utils.ProbeURL(context.Background(), server.URL, utils.ProbeOptions{})
+	assert.Equal(t, proto.StatusReport_DOWN, report.Health)
+
+	report = utils.ProbeURL(context.Background(), server.URL, utils.ProbeOptions{InsecureSkipVerify: true})
+	assert.Equal(t, proto.StatusReport_READY, report.Health)
+}
+
+func TestProbeURLTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	report := utils.ProbeURL(context.Background(), server.URL, utils.ProbeOptions{Timeout: 50 * time.Millisecond})
+	assert.Equal(t, proto.StatusReport_DOWN, report.Health)
+	assert.Contains(t, report.HealthMessage, "is unreachable")
+}
@@ -0,0 +1,365 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+)
+
+// RedactedValue replaces secrets in logs and UI output
+const RedactedValue = "********"
+
+// MinRedactedLength is the minimum length of the values guessed to be secrets. Shorter values (e.g. "1"
+// or "true" in env) would mask unrelated parts of the output, and are not worth hiding.
+const MinRedactedLength = 6
+
+// sensitiveKeys are parts of the log field names whose values are always redacted
+var sensitiveKeys = []string{"password", "secret", "token", "credential", "auth"}
+
+// IsSensitiveKey reports whether a log field or variable named key holds a secret
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redactor masks secrets in strings, it is safe for concurrent use. A nil Redactor doesn't mask anything.
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  map[string]bool
+	replacer *strings.Replacer
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{secrets: map[string]bool{}}
+}
+
+// Add registers values guessed to be secrets, e.g. by their name, that are masked from now on. Values
+// shorter than MinRedactedLength are ignored.
+func (r *Redactor) Add(secrets ...string) {
+	r.add(MinRedactedLength, secrets)
+}
+
+// AddExplicit registers values known to be secrets, e.g. passwords or CredHub credentials, that are
+// masked from now on whatever their length. Only empty values are ignored.
+func (r *Redactor) AddExplicit(secrets ...string) {
+	r.add(1, secrets)
+}
+
+func (r *Redactor) add(minLength int, secrets []string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := false
+	for _, s := range secrets {
+		if len(s) < minLength || r.secrets[s] {
+			continue
+		}
+		r.secrets[s] = true
+		changed = true
+	}
+	if !changed {
+		return
+	}
+
+	// The longest secrets are replaced first, so that a secret containing another one is fully masked
+	var sorted []string
+	for s := range r.secrets {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	var oldnew []string
+	for _, s := range sorted {
+		oldnew = append(oldnew, s, RedactedValue)
+	}
+	r.replacer = strings.NewReplacer(oldnew...)
+}
+
+// AddCredentials registers the string values of service credentials, including nested ones
+func (r *Redactor) AddCredentials(credentials map[string]interface{}) {
+	var values []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch value := v.(type) {
+		case string:
+			values = append(values, value)
+		case map[string]interface{}:
+			for _, nested := range value {
+				walk(nested)
+			}
+		case []interface{}:
+			for _, nested := range value {
+				walk(nested)
+			}
+		}
+	}
+	walk(credentials)
+	r.Add(values...)
+}
+
+// Redact masks the registered secrets in s
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// RedactError masks the registered secrets in the message of err. The original error can still
+// be retrieved with errors.Unwrap.
+func (r *Redactor) RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := r.Redact(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactValue masks a log field value. Values are only replaced by their string form when
+// they contain a secret, so that the other ones keep their formatting.
+func (r *Redactor) redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return r.Redact(value)
+	case error:
+		return r.RedactError(value)
+	}
+	s := fmt.Sprintf("%v", v)
+	if redacted := r.Redact(s); redacted != s {
+		return redacted
+	}
+	return v
+}
+
+// redactArgs masks the values of key-value pairs of log fields
+func (r *Redactor) redactArgs(args []interface{}) []interface{} {
+	result := make([]interface{}, len(args))
+	for i := 0; i < len(args); i++ {
+		if i%2 == 1 {
+			if key, ok := args[i-1].(string); ok && IsSensitiveKey(key) {
+				result[i] = MaskValue(fmt.Sprintf("%v", args[i]))
+				continue
+			}
+		}
+		result[i] = r.redactValue(args[i])
+	}
+	return result
+}
+
+// redactingLogger is a hclog.Logger masking secrets in messages and fields
+type redactingLogger struct {
+	hclog.Logger
+	redactor *Redactor
+}
+
+// NewRedactingLogger returns a logger masking the secrets of redactor, and the values of the fields with a
+// sensitive name (see IsSensitiveKey)
+func NewRedactingLogger(logger hclog.Logger, redactor *Redactor) hclog.Logger {
+	if l, ok := logger.(*redactingLogger); ok && l.redactor == redactor {
+		return logger
+	}
+	return &redactingLogger{Logger: logger, redactor: redactor}
+}
+
+func (l *redactingLogger) Log(level hclog.Level, msg string, args ...interface{}) {
+	l.Logger.Log(level, l.redactor.Redact(msg), l.redactor.redactArgs(args)...)
+}
+
+func (l *redactingLogger) Trace(msg string, args ...interface{}) {
+	l.Logger.Trace(l.redactor.Redact(msg), l.redactor.redactArgs(args)...)
+}
+
+func (l *redactingLogger) Debug(msg string, args ...interface{}) {
+	l.Logger.Debug(l.redactor.Redact(msg), l.redactor.redactArgs(args)...)
+}
+
+func (l *redactingLogger) Info(msg string, args ...interface{}) {
+	l.Logger.Info(l.redactor.Redact(msg), l.redactor.redactArgs(args)...)
+}
+
+func (l *redactingLogger) Warn(msg string, args ...interface{}) {
+	l.Logger.Warn(l.redactor.Redact(msg), l.redactor.redactArgs(args)...)
+}
+
+func (l *redactingLogger) Error(msg string, args ...interface{}) {
+	l.Logger.Error(l.redactor.Redact(msg), l.redactor.redactArgs(args)...)
+}
+
+func (l *redactingLogger) With(args ...interface{}) hclog.Logger {
+	return &redactingLogger{Logger: l.Logger.With(l.redactor.redactArgs(args)...), redactor: l.redactor}
+}
+
+func (l *redactingLogger) Named(name string) hclog.Logger {
+	return &redactingLogger{Logger: l.Logger.Named(name), redactor: l.redactor}
+}
+
+func (l *redactingLogger) ResetNamed(name string) hclog.Logger {
+	return &redactingLogger{Logger: l.Logger.ResetNamed(name), redactor: l.redactor}
+}
+
+// redactingWriter masks secrets in the data written to w. Secrets split across several writes are not masked.
+type redactingWriter struct {
+	w        io.Writer
+	redactor *Redactor
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	_, err := w.w.Write([]byte(w.redactor.Redact(string(p))))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redactMessage formats a UI message with its arguments and masks its secrets. The options are returned
+// separately, to be passed again with the formatted message.
+func (r *Redactor) redactMessage(msg string, raw []interface{}) (string, []interface{}) {
+	var args []interface{}
+	var opts []interface{}
+	for _, a := range raw {
+		if opt, ok := a.(terminal.Option); ok {
+			opts = append(opts, opt)
+		} else {
+			args = append(args, a)
+		}
+	}
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	return r.Redact(msg), opts
+}
+
+// redactingUI is a terminal.UI masking secrets in its output
+type redactingUI struct {
+	terminal.UI
+	redactor *Redactor
+}
+
+// NewRedactingUI returns a UI masking the secrets of redactor in messages, steps, status and tables
+func NewRedactingUI(ui terminal.UI, redactor *Redactor) terminal.UI {
+	if u, ok := ui.(*redactingUI); ok && u.redactor == redactor {
+		return ui
+	}
+	return &redactingUI{UI: ui, redactor: redactor}
+}
+
+func (u *redactingUI) Output(msg string, raw ...interface{}) {
+	msg, opts := u.redactor.redactMessage(msg, raw)
+	u.UI.Output("%s", append([]interface{}{msg}, opts...)...)
+}
+
+func (u *redactingUI) NamedValues(rows []terminal.NamedValue, opts ...terminal.Option) {
+	redacted := make([]terminal.NamedValue, len(rows))
+	for i, row := range rows {
+		redacted[i] = terminal.NamedValue{Name: row.Name, Value: u.redactor.redactValue(row.Value)}
+		if IsSensitiveKey(row.Name) {
+			redacted[i].Value = MaskValue(fmt.Sprintf("%v", row.Value))
+		}
+	}
+	u.UI.NamedValues(redacted, opts...)
+}
+
+func (u *redactingUI) OutputWriters() (io.Writer, io.Writer, error) {
+	stdout, stderr, err := u.UI.OutputWriters()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &redactingWriter{w: stdout, redactor: u.redactor}, &redactingWriter{w: stderr, redactor: u.redactor}, nil
+}
+
+func (u *redactingUI) Status() terminal.Status {
+	return &redactingStatus{Status: u.UI.Status(), redactor: u.redactor}
+}
+
+func (u *redactingUI) Table(table *terminal.Table, opts ...terminal.Option) {
+	redacted := &terminal.Table{Headers: table.Headers}
+	for _, row := range table.Rows {
+		var redactedRow []terminal.TableEntry
+		for _, entry := range row {
+			entry.Value = u.redactor.Redact(entry.Value)
+			redactedRow = append(redactedRow, entry)
+		}
+		redacted.Rows = append(redacted.Rows, redactedRow)
+	}
+	u.UI.Table(redacted, opts...)
+}
+
+func (u *redactingUI) StepGroup() terminal.StepGroup {
+	return &redactingStepGroup{StepGroup: u.UI.StepGroup(), redactor: u.redactor}
+}
+
+type redactingStatus struct {
+	terminal.Status
+	redactor *Redactor
+}
+
+func (s *redactingStatus) Update(msg string) {
+	s.Status.Update(s.redactor.Redact(msg))
+}
+
+func (s *redactingStatus) Step(status, msg string) {
+	s.Status.Step(status, s.redactor.Redact(msg))
+}
+
+type redactingStepGroup struct {
+	terminal.StepGroup
+	redactor *Redactor
+}
+
+func (g *redactingStepGroup) Add(msg string, raw ...interface{}) terminal.Step {
+	msg, _ = g.redactor.redactMessage(msg, raw)
+	return &redactingStep{Step: g.StepGroup.Add("%s", msg), redactor: g.redactor}
+}
+
+type redactingStep struct {
+	terminal.Step
+	redactor *Redactor
+}
+
+func (s *redactingStep) TermOutput() io.Writer {
+	return &redactingWriter{w: s.Step.TermOutput(), redactor: s.redactor}
+}
+
+func (s *redactingStep) Update(msg string, raw ...interface{}) {
+	msg, _ = s.redactor.redactMessage(msg, raw)
+	s.Step.Update("%s", msg)
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ValidateRoutePath checks that a route path is accepted by Cloud Foundry.
+// An empty path is valid and means that the route has no path.
+func ValidateRoutePath(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("route path %q must begin with /", path)
+	}
+
+	if path == "/" {
+		return fmt.Errorf("route path cannot be exactly /")
+	}
+
+	if strings.Contains(path, "?") {
+		return fmt.Errorf("route path %q cannot contain ?", path)
+	}
+
+	return nil
+}
+
+// RouteURL returns the URL of a route, without the protocol. TCP routes have no
+// host and path but a port instead.
+func RouteURL(host string, domain string, path string, port int) string {
+	url := domain
+	if host != "" {
+		url = fmt.Sprintf("%s.%s", host, domain)
+	}
+	url += path
+	if port != 0 {
+		url = fmt.Sprintf("%s:%d", url, port)
+	}
+	return url
+}
+
+// ParsePortRange parses a single port ("8080") or a port range ("8080-8090")
+func ParsePortRange(s string) (start int, end int, err error) {
+	parts := strings.SplitN(s, "-", 2)
+	start, err = parsePort(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end = start
+	if len(parts) == 2 {
+		end, err = parsePort(parts[1])
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if end < start {
+		return 0, 0, fmt.Errorf("invalid port range %q: end port is lower than start port", s)
+	}
+	return start, end, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("port %d is not in range 1-65535", port)
+	}
+	return port, nil
+}
+
+// ValidateRouteOptions checks the load-balancing algorithm of a route and the
+// protocol of its destination. Empty values are valid and mean the CF defaults.
+func ValidateRouteOptions(loadBalancing string, protocol string) error {
+	switch loadBalancing {
+	case "", "round-robin", "least-connection":
+	default:
+		return fmt.Errorf("loadbalancing has to be round-robin or least-connection, got %q", loadBalancing)
+	}
+
+	switch protocol {
+	case "", "http1", "http2":
+	default:
+		return fmt.Errorf("protocol has to be http1 or http2, got %q", protocol)
+	}
+	return nil
+}
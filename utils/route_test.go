@@ -0,0 +1,50 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swisscom/waypoint-plugin-cloudfoundry/utils"
+)
+
+func TestValidateRoutePath(t *testing.T) {
+	assert.NoError(t, utils.ValidateRoutePath(""))
+	assert.NoError(t, utils.ValidateRoutePath("/api"))
+	assert.NoError(t, utils.ValidateRoutePath("/api/v1"))
+
+	assert.Error(t, utils.ValidateRoutePath("api"))
+	assert.Error(t, utils.ValidateRoutePath("/"))
+	assert.Error(t, utils.ValidateRoutePath("/api?version=1"))
+}
+
+func TestRouteURL(t *testing.T) {
+	assert.Equal(t, "app.example.com", utils.RouteURL("app", "example.com", "", 0))
+	assert.Equal(t, "app.example.com/api", utils.RouteURL("app", "example.com", "/api", 0))
+	assert.Equal(t, "tcp.example.com:1024", utils.RouteURL("", "tcp.example.com", "", 1024))
+}
+
+func TestParsePortRange(t *testing.T) {
+	start, end, err := utils.ParsePortRange("8080")
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, start)
+	assert.Equal(t, 8080, end)
+
+	start, end, err = utils.ParsePortRange("8080-8090")
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, start)
+	assert.Equal(t, 8090, end)
+
+	for _, invalid := range []string{"", "http", "0", "65536", "8090-8080", "8080-"} {
+		_, _, err = utils.ParsePortRange(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestValidateRouteOptions(t *testing.T) {
+	assert.NoError(t, utils.ValidateRouteOptions("", ""))
+	assert.NoError(t, utils.ValidateRouteOptions("round-robin", "http1"))
+	assert.NoError(t, utils.ValidateRouteOptions("least-connection", "http2"))
+
+	assert.Error(t, utils.ValidateRouteOptions("random", ""))
+	assert.Error(t, utils.ValidateRouteOptions("", "grpc"))
+}